@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AnimationOptions - параметры записи анимации поиска
+type AnimationOptions struct {
+	// CellSize - сторона клетки в пикселях; 0 - подбирается так, чтобы
+	// большая сторона кадра была около 512 пикселей. Кадр не бывает больше
+	// maxFrameSide пикселей по стороне: на больших картах клетка
+	// становится меньше пикселя, и несколько клеток сводятся в один.
+	CellSize int
+	// Every - кадр через каждые Every раскрытий; 0 - подбирается так,
+	// чтобы кадров было не больше MaxFrames
+	Every int
+	// MaxFrames ограничивает число промежуточных кадров; 0 - 200. При
+	// заданном Every запись кадров после MaxFrames прекращается.
+	MaxFrames int
+	// Delay и FinalDelay - задержка кадра и последнего кадра в сотых долях
+	// секунды; 0 - 5 и 200
+	Delay, FinalDelay int
+	Palette           CellPalette
+}
+
+// maxFrameSide - наибольшая сторона кадра анимации в пикселях
+const maxFrameSide = 1024
+
+func (o AnimationOptions) withDefaults(grid *Grid) AnimationOptions {
+	side := max(grid.Width, grid.Height)
+	if o.CellSize <= 0 {
+		o.CellSize = max(1, 512/side)
+	}
+	o.CellSize = max(1, min(o.CellSize, maxFrameSide/side))
+	if o.MaxFrames <= 0 {
+		o.MaxFrames = 200
+	}
+	if o.Delay <= 0 {
+		o.Delay = 5
+	}
+	if o.FinalDelay <= 0 {
+		o.FinalDelay = 200
+	}
+	o.Palette = o.Palette.withDefaults()
+	return o
+}
+
+// SearchAnimation - последовательность кадров поиска
+type SearchAnimation struct {
+	Frames []*image.Paletted
+	Delays []int // задержки кадров в сотых долях секунды
+}
+
+// frameRenderer рисует кадры по текущему состоянию Searcher
+type frameRenderer struct {
+	s     *Searcher
+	size  int             // пикселей на клетку
+	scale int             // клеток на пиксель по стороне, если size = 1
+	base  *image.Paletted // препятствия и рельеф, общие для всех кадров
+}
+
+func newFrameRenderer(s *Searcher, opts AnimationOptions) *frameRenderer {
+	grid := s.grid
+	colors := opts.Palette.Colors()[:int(cellStates)+terrainLevels]
+	pal := make(color.Palette, len(colors))
+	copy(pal, colors)
+
+	r := &frameRenderer{s: s, size: opts.CellSize, scale: 1}
+	if side := max(grid.Width, grid.Height); side > maxFrameSide {
+		r.scale = (side + maxFrameSide - 1) / maxFrameSide
+	}
+	w := (grid.Width + r.scale - 1) / r.scale * r.size
+	h := (grid.Height + r.scale - 1) / r.scale * r.size
+	r.base = image.NewPaletted(image.Rect(0, 0, w, h), pal)
+	lo, hi := terrainRange(grid)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			p := Point{x, y}
+			idx := int(CellFree)
+			if grid.IsObstacle(p) {
+				idx = int(CellObstacle)
+			} else if cost := grid.Cost(p); cost > 1 {
+				idx = int(cellStates) + terrainLevel(cost, lo, hi)
+			}
+			r.fill(r.base, p, uint8(idx))
+		}
+	}
+	return r
+}
+
+// fillRank - приоритет цвета в уменьшенном кадре, где в пиксель
+// попадает несколько клеток: путь и концы поиска важнее открытого и
+// закрытого списков, а те важнее карты
+func fillRank(idx uint8) int {
+	switch CellState(idx) {
+	case CellFree:
+		return 0
+	case CellObstacle:
+		return 2
+	case CellClosed:
+		return 3
+	case CellOpen:
+		return 4
+	case CellPath:
+		return 5
+	case CellStart, CellGoal:
+		return 6
+	}
+	return 1 // рельеф
+}
+
+// fill закрашивает клетку p цветом палитры idx; клетки вне сетки
+// пропускаются
+func (r *frameRenderer) fill(img *image.Paletted, p Point, idx uint8) {
+	if !r.s.grid.InBounds(p) {
+		return
+	}
+	if r.scale > 1 {
+		i := p.y/r.scale*img.Stride + p.x/r.scale
+		if fillRank(idx) >= fillRank(img.Pix[i]) {
+			img.Pix[i] = idx
+		}
+		return
+	}
+	for y := p.y * r.size; y < (p.y+1)*r.size; y++ {
+		row := img.Pix[y*img.Stride+p.x*r.size : y*img.Stride+(p.x+1)*r.size]
+		for i := range row {
+			row[i] = idx
+		}
+	}
+}
+
+// frame рисует открытый и закрытый списки, путь до узла current
+// и концы поиска
+func (r *frameRenderer) frame(current *Node, start, goal Point) *image.Paletted {
+	img := image.NewPaletted(r.base.Rect, r.base.Palette)
+	copy(img.Pix, r.base.Pix)
+
+	s := r.s
+	for i := range s.nodes {
+		if s.stamp[i] != s.gen {
+			continue
+		}
+		switch s.state[i] {
+		case cellOpen:
+			r.fill(img, s.nodes[i].Position, uint8(CellOpen))
+		case cellClosed:
+			r.fill(img, s.nodes[i].Position, uint8(CellClosed))
+		}
+	}
+	for n := current; n != nil; n = n.Parent {
+		r.fill(img, n.Position, uint8(CellPath))
+	}
+	r.fill(img, start, uint8(CellStart))
+	r.fill(img, goal, uint8(CellGoal))
+	return img
+}
+
+// RecordSearch выполняет поиск от start до goal, записывая кадр через
+// каждые opts.Every раскрытий и итоговый кадр с найденным путем.
+// Наблюдатель Searcher на время записи дополняется записью кадров и
+// затем восстанавливается; пробный поиск при Every = 0 ему не виден.
+// Если конец поиска вне сетки или на препятствии, кадров нет и
+// возвращается ErrInvalidPoint.
+func RecordSearch(s *Searcher, start, goal Point, opts AnimationOptions) (*SearchAnimation, []*Node, error) {
+	if s.grid == nil {
+		return nil, nil, errors.New("search animation needs a Grid searcher")
+	}
+	if !s.grid.IsValid(start) {
+		return nil, nil, fmt.Errorf("start %w: (%d,%d)", ErrInvalidPoint, start.x, start.y)
+	}
+	if !s.grid.IsValid(goal) {
+		return nil, nil, fmt.Errorf("goal %w: (%d,%d)", ErrInvalidPoint, goal.x, goal.y)
+	}
+	opts = opts.withDefaults(s.grid)
+	prev := s.observer
+	defer s.SetObserver(prev)
+	every := opts.Every
+	if every <= 0 {
+		// Пробный поиск без кадров определяет общее число раскрытий
+		s.SetObserver(nil)
+		s.Search(start, goal)
+		every = max(1, int(math.Ceil(float64(s.Stats().Expanded)/float64(opts.MaxFrames))))
+	}
+
+	r := newFrameRenderer(s, opts)
+	anim := &SearchAnimation{}
+	add := func(img *image.Paletted, delay int) {
+		anim.Frames = append(anim.Frames, img)
+		anim.Delays = append(anim.Delays, delay)
+	}
+
+	var last *Node
+	s.SetObserver(MultiObserver(prev, ObserverFuncs{Pop: func(current *Node) {
+		last = current
+		if (s.stats.Expanded-1)%every == 0 && len(anim.Frames) < opts.MaxFrames {
+			add(r.frame(current, start, goal), opts.Delay)
+		}
+	}}))
+
+	path, err := s.Search(start, goal)
+	if err == nil {
+		last = path[len(path)-1]
+	}
+	add(r.frame(last, start, goal), opts.FinalDelay)
+	return anim, path, err
+}
+
+// WriteGIF кодирует анимацию в GIF с бесконечным повтором
+func (a *SearchAnimation) WriteGIF(w io.Writer) error {
+	return gif.EncodeAll(w, &gif.GIF{Image: a.Frames, Delay: a.Delays})
+}
+
+// WritePNGFrames сохраняет кадры в каталог dir как frame_00001.png, ...
+// и возвращает имена файлов
+func (a *SearchAnimation) WritePNGFrames(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var names []string
+	for i, img := range a.Frames {
+		name := filepath.Join(dir, fmt.Sprintf("frame_%05d.png", i+1))
+		f, err := os.Create(name)
+		if err != nil {
+			return names, err
+		}
+		err = png.Encode(f, img)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Save сохраняет анимацию: файл .gif - анимированный GIF,
+// любой другой путь - каталог с пронумерованными кадрами PNG
+func (a *SearchAnimation) Save(path string) error {
+	if strings.ToLower(filepath.Ext(path)) != ".gif" {
+		_, err := a.WritePNGFrames(path)
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = a.WriteGIF(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
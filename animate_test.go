@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestRecordSearchInvalidEndpoints проверяет, что концы вне сетки или на
+// препятствии дают ErrInvalidPoint без кадров, а не панику отрисовки
+func TestRecordSearchInvalidEndpoints(t *testing.T) {
+	grid := NewGrid(4, 4)
+	grid.AddObstacle(Point{1, 1})
+	tests := []struct {
+		name        string
+		start, goal Point
+	}{
+		{"start left of the grid", Point{-3, 0}, Point{2, 2}},
+		{"start below the grid", Point{0, 4}, Point{2, 2}},
+		{"goal outside", Point{0, 0}, Point{9, 9}},
+		{"goal on an obstacle", Point{0, 0}, Point{1, 1}},
+	}
+	for _, tt := range tests {
+		for _, every := range []int{0, 1} {
+			anim, path, err := RecordSearch(NewSearcher(grid), tt.start, tt.goal, AnimationOptions{Every: every})
+			if !errors.Is(err, ErrInvalidPoint) || anim != nil || path != nil {
+				t.Errorf("%s, every %d: got %v, %v, %v; want ErrInvalidPoint and no frames", tt.name, every, anim, path, err)
+			}
+		}
+	}
+}
+
+// TestRecordSearchObserver проверяет, что наблюдатель вызывающего видит
+// записываемый поиск и восстанавливается после него
+func TestRecordSearchObserver(t *testing.T) {
+	grid := NewGrid(6, 6)
+	s := NewSearcher(grid)
+	pops := 0
+	observer := ObserverFuncs{Pop: func(*Node) { pops++ }}
+	s.SetObserver(observer)
+
+	anim, path, err := RecordSearch(s, Point{0, 0}, Point{5, 5}, AnimationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Frames) < 2 || len(anim.Frames) != len(anim.Delays) {
+		t.Errorf("got %d frames and %d delays", len(anim.Frames), len(anim.Delays))
+	}
+	if pops != s.Stats().Expanded {
+		t.Errorf("caller's observer saw %d pops, want %d", pops, s.Stats().Expanded)
+	}
+	if len(path) == 0 {
+		t.Error("no path")
+	}
+	if _, ok := s.observer.(ObserverFuncs); !ok {
+		t.Errorf("observer after recording: %T, want the caller's ObserverFuncs", s.observer)
+	}
+}
+
+// TestRecordSearchFrameSize проверяет ограничение стороны кадра на
+// больших картах
+func TestRecordSearchFrameSize(t *testing.T) {
+	grid := NewGrid(3000, 40)
+	anim, _, err := RecordSearch(NewSearcher(grid), Point{0, 0}, Point{2999, 39}, AnimationOptions{Every: 1000, CellSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range anim.Frames {
+		if b := f.Bounds(); b.Dx() > maxFrameSide || b.Dy() > maxFrameSide {
+			t.Fatalf("frame %d is %dx%d, want at most %d on a side", i, b.Dx(), b.Dy(), maxFrameSide)
+		}
+	}
+}
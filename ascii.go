@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Символы текстового формата сетки
+const (
+	asciiWall  = '#'
+	asciiFree  = '.'
+	asciiStart = 'S'
+	asciiGoal  = 'G'
+)
+
+// ParseASCII читает сетку из текста: '#' - стена, '.' - свободная клетка,
+// 'S'/'G' - старт и цель, цифры 1-9 - стоимость рельефа.
+// Первая строка соответствует y = 0. Пустые строки в конце игнорируются.
+func ParseASCII(r io.Reader) (*MapFile, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<24)
+
+	var rows []string
+	for scanner.Scan() {
+		rows = append(rows, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty map")
+	}
+
+	width := len(rows[0])
+	m := &MapFile{Grid: NewGrid(width, len(rows))}
+	for y, row := range rows {
+		if len(row) != width {
+			return nil, fmt.Errorf("line %d: row has %d cells, expected %d", y+1, len(row), width)
+		}
+		for x := 0; x < width; x++ {
+			p := Point{x, y}
+			switch c := row[x]; {
+			case c == asciiWall:
+				m.Grid.AddObstacle(p)
+			case c == asciiFree:
+			case c == asciiStart || c == asciiGoal:
+				marker := &m.Start
+				if c == asciiGoal {
+					marker = &m.Goal
+				}
+				if *marker != nil {
+					return nil, fmt.Errorf("line %d: duplicate %q marker at column %d", y+1, c, x+1)
+				}
+				*marker = &Point{x, y}
+			case c >= '1' && c <= '9':
+				m.Grid.SetCost(p, float64(c-'0'))
+			default:
+				return nil, fmt.Errorf("line %d: unexpected %q at column %d", y+1, c, x+1)
+			}
+		}
+	}
+	return m, nil
+}
+
+// LoadASCII читает текстовую карту с диска
+func LoadASCII(path string) (*MapFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ParseASCII(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// asciiCell возвращает символ клетки без меток старта и цели.
+// Дробная стоимость округляется и ограничивается диапазоном 1-9.
+func asciiCell(grid *Grid, p Point) byte {
+	if grid.IsObstacle(p) {
+		return asciiWall
+	}
+	cost := math.Round(grid.Cost(p))
+	if cost <= 1 {
+		return asciiFree
+	}
+	return '0' + byte(min(cost, 9))
+}
+
+// WriteASCII записывает сетку в текстовом формате ParseASCII.
+// start и goal могут быть nil.
+func WriteASCII(w io.Writer, grid *Grid, start, goal *Point) error {
+	bw := bufio.NewWriter(w)
+	row := make([]byte, grid.Width+1)
+	row[grid.Width] = '\n'
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			p := Point{x, y}
+			switch {
+			case start != nil && p == *start:
+				row[x] = asciiStart
+			case goal != nil && p == *goal:
+				row[x] = asciiGoal
+			default:
+				row[x] = asciiCell(grid, p)
+			}
+		}
+		bw.Write(row)
+	}
+	return bw.Flush()
+}
+
+// String возвращает сетку в текстовом формате
+func (g *Grid) String() string {
+	var sb strings.Builder
+	WriteASCII(&sb, g, nil, nil)
+	return sb.String()
+}
+
+// ===================================================== //
+// Вывод в терминал //
+
+// ANSI-последовательности для цветного вывода
+const (
+	ansiReset  = "\x1b[0m"
+	ansiWall   = "\x1b[100m"     // серый фон
+	ansiPath   = "\x1b[1;33m"    // желтый
+	ansiOpen   = "\x1b[32m"      // зеленый
+	ansiClosed = "\x1b[34m"      // синий
+	ansiStart  = "\x1b[1;30;42m" // черный на зеленом
+	ansiGoal   = "\x1b[1;30;41m" // черный на красном
+	ansiCost   = "\x1b[2m"       // приглушенный
+)
+
+// TerminalView - слои, накладываемые на сетку при выводе в терминал
+type TerminalView struct {
+	Path        []*Node
+	Start, Goal *Point  // метки; по умолчанию - концы пути
+	Open        []Point // открытый список (фронт поиска)
+	Closed      []Point // раскрытые узлы
+	NoColor     bool    // выводить без ANSI-цветов
+}
+
+// RenderTerminal печатает сетку с путем, открытым и закрытым множествами.
+// Приоритет слоев: старт/цель, путь, открытые, закрытые, рельеф.
+func RenderTerminal(w io.Writer, grid *Grid, view TerminalView) error {
+	const (
+		layerNone = iota
+		layerClosed
+		layerOpen
+		layerPath
+	)
+	layers := make([]uint8, grid.Width*grid.Height)
+	mark := func(p Point, layer uint8) {
+		if grid.InBounds(p) {
+			layers[grid.index(p)] = max(layers[grid.index(p)], layer)
+		}
+	}
+	for _, p := range view.Closed {
+		mark(p, layerClosed)
+	}
+	for _, p := range view.Open {
+		mark(p, layerOpen)
+	}
+	for _, node := range view.Path {
+		mark(node.Position, layerPath)
+	}
+
+	start, goal := view.Start, view.Goal
+	if len(view.Path) > 0 {
+		if start == nil {
+			start = &view.Path[0].Position
+		}
+		if goal == nil {
+			goal = &view.Path[len(view.Path)-1].Position
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			p := Point{x, y}
+			symbol, style := asciiCell(grid, p), ""
+			switch {
+			case start != nil && p == *start:
+				symbol, style = asciiStart, ansiStart
+			case goal != nil && p == *goal:
+				symbol, style = asciiGoal, ansiGoal
+			case symbol == asciiWall:
+				style = ansiWall
+			case layers[grid.index(p)] == layerPath:
+				symbol, style = '*', ansiPath
+			case layers[grid.index(p)] == layerOpen:
+				symbol, style = 'o', ansiOpen
+			case layers[grid.index(p)] == layerClosed:
+				symbol, style = 'x', ansiClosed
+			case symbol != asciiFree:
+				style = ansiCost
+			}
+
+			if view.NoColor || style == "" {
+				bw.WriteByte(symbol)
+			} else {
+				bw.WriteString(style)
+				bw.WriteByte(symbol)
+				bw.WriteString(ansiReset)
+			}
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// BenchMap - карта с запросом для набора замеров RunSearchBenchmarks
+type BenchMap struct {
+	Name        string
+	Grid        *Grid
+	Start, Goal Point
+}
+
+// BenchOptions - настройки RunSearchBenchmarks
+type BenchOptions struct {
+	Algorithms []Algorithm // по умолчанию все
+	Queues     []QueueKind // по умолчанию все
+	Movement   Movement
+	Weight     float64       // вес для AlgorithmWeighted; 0 - 1.5
+	MinTime    time.Duration // время замера одной комбинации; 0 - 200 мс
+}
+
+func (o BenchOptions) withDefaults() BenchOptions {
+	if len(o.Algorithms) == 0 {
+		for i := range algorithmNames {
+			o.Algorithms = append(o.Algorithms, Algorithm(i))
+		}
+	}
+	if len(o.Queues) == 0 {
+		o.Queues = QueueKinds()
+	}
+	if o.Weight <= 0 {
+		o.Weight = 1.5
+	}
+	if o.MinTime <= 0 {
+		o.MinTime = 200 * time.Millisecond
+	}
+	return o
+}
+
+// GeneratedBenchMaps строит воспроизводимый набор карт size x size для
+// bench: случайные препятствия, лабиринт, пещера и рельеф со стоимостями
+func GeneratedBenchMaps(size int, seed int64) ([]BenchMap, error) {
+	kinds := []struct {
+		generator string
+		opts      GenerateOptions
+	}{
+		{"random", GenerateOptions{Density: 0.2}},
+		{"maze-backtracker", GenerateOptions{Braid: 0.2}},
+		{"cave", GenerateOptions{Density: 0.45}},
+		{"terrain", GenerateOptions{Density: 0.2}},
+	}
+	var maps []BenchMap
+	for _, k := range kinds {
+		opts := k.opts
+		opts.Width, opts.Height, opts.Seed, opts.Connect = size, size, seed, true
+		m, err := Generate(k.generator, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k.generator, err)
+		}
+		maps = append(maps, BenchMap{
+			Name:  fmt.Sprintf("%s-%dx%d", k.generator, size, size),
+			Grid:  m.Grid,
+			Start: *m.Start,
+			Goal:  *m.Goal,
+		})
+	}
+	return maps, nil
+}
+
+// RunSearchBenchmarks замеряет каждую комбинацию алгоритма и очереди на
+// каждой карте: поиск повторяется на одном Searcher не меньше MinTime.
+// Колонка "Δcost" - отличие стоимости пути от двоичной кучи с тем же
+// алгоритмом: очереди bucket и radix округляют ключи, поэтому на
+// дробных стоимостях их путь бывает дороже, а greedy и weighted
+// зависят еще и от порядка равных ключей. Алгоритмы, не нашедшие пути
+// на карте, пропускаются с сообщением.
+func RunSearchBenchmarks(w io.Writer, maps []BenchMap, opts BenchOptions) {
+	opts = opts.withDefaults()
+	fmt.Fprintf(w, "%-24s %-9s %-9s %8s %12s %10s %12s %10s\n",
+		"map", "algorithm", "queue", "runs", "µs/op", "expanded", "cost", "Δcost")
+	for _, bm := range maps {
+		for _, alg := range opts.Algorithms {
+			ref := NewSearcher(bm.Grid)
+			ref.SetMovement(opts.Movement)
+			ref.SetAlgorithm(alg, opts.Weight)
+			refPath, err := ref.Search(bm.Start, bm.Goal)
+			if err != nil {
+				fmt.Fprintf(w, "%-24s %-9s пропущен: %v\n", bm.Name, alg, err)
+				continue
+			}
+			reference := refPath[len(refPath)-1].GCost
+
+			for _, kind := range opts.Queues {
+				s := NewSearcher(bm.Grid)
+				s.SetMovement(opts.Movement)
+				s.SetAlgorithm(alg, opts.Weight)
+				s.SetQueue(NewPriorityQueue(kind))
+				path, err := s.Search(bm.Start, bm.Goal) // прогрев: пулы узлов и буфер пути
+				if err != nil {
+					fmt.Fprintf(w, "%-24s %-9s %-9s ошибка: %v\n", bm.Name, alg, kind, err)
+					continue
+				}
+				cost := path[len(path)-1].GCost
+				expanded := s.Stats().Expanded
+
+				runs := 0
+				begin := time.Now()
+				for elapsed := time.Duration(0); elapsed < opts.MinTime; elapsed = time.Since(begin) {
+					s.Search(bm.Start, bm.Goal)
+					runs++
+				}
+				perOp := float64(time.Since(begin).Microseconds()) / float64(runs)
+				fmt.Fprintf(w, "%-24s %-9s %-9s %8d %12.1f %10d %12.2f %10.3g\n",
+					bm.Name, alg, kind, runs, perOp, expanded, cost, cost-reference)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchCase - сетка и запрос для бенчмарка
+type benchCase struct {
+	name        string
+	grid        *Grid
+	start, goal Point
+}
+
+// benchCases строит воспроизводимый набор карт для бенчмарков
+func benchCases() []benchCase {
+	open := NewGrid(100, 100)
+
+	walls := NewGrid(100, 100)
+	for y := 0; y < 90; y++ {
+		walls.AddObstacle(Point{25, y})
+		walls.AddObstacle(Point{75, y})
+	}
+	for y := 10; y < 100; y++ {
+		walls.AddObstacle(Point{50, y})
+	}
+
+	random := NewGrid(256, 256)
+	rng := rand.New(rand.NewSource(2))
+	for x := 0; x < random.Width; x++ {
+		for y := 0; y < random.Height; y++ {
+			if rng.Float64() < 0.2 {
+				random.AddObstacle(Point{x, y})
+			}
+		}
+	}
+	random.RemoveObstacle(Point{0, 0})
+	random.RemoveObstacle(Point{255, 255})
+
+	return []benchCase{
+		{"open-100x100", open, Point{0, 0}, Point{99, 99}},
+		{"walls-100x100", walls, Point{0, 0}, Point{99, 99}},
+		{"random20-256x256", random, Point{0, 0}, Point{255, 255}},
+	}
+}
+
+// BenchmarkAStar - одноразовый AStar: пулы выделяются на каждый запрос
+func BenchmarkAStar(b *testing.B) {
+	for _, bc := range benchCases() {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := AStar(bc.grid, bc.start, bc.goal); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSearcher - переиспользуемый Searcher без аллокаций на запрос
+func BenchmarkSearcher(b *testing.B) {
+	for _, bc := range benchCases() {
+		b.Run(bc.name, func(b *testing.B) {
+			searcher := NewSearcher(bc.grid)
+			searcher.Search(bc.start, bc.goal) // буфер пути выделяется один раз
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := searcher.Search(bc.start, bc.goal); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkQueues сравнивает реализации открытого списка; стоимость пути
+// сверяется с бинарной кучей
+func BenchmarkQueues(b *testing.B) {
+	for _, bc := range benchCases() {
+		reference, err := AStar(bc.grid, bc.start, bc.goal)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, kind := range QueueKinds() {
+			b.Run(kind.String()+"/"+bc.name, func(b *testing.B) {
+				searcher := NewSearcher(bc.grid)
+				searcher.SetQueue(NewPriorityQueue(kind))
+				path, err := searcher.Search(bc.start, bc.goal)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if cost, want := path[len(path)-1].GCost, reference[len(reference)-1].GCost; cost != want {
+					b.Fatalf("wrong path: cost %g instead of %g", cost, want)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					searcher.Search(bc.start, bc.goal)
+				}
+			})
+		}
+	}
+}
+
+// TestRunSearchBenchmarks проверяет набор замеров CLI: строка на каждую
+// комбинацию карты, алгоритма и очереди, двоичная куча - эталон стоимости
+func TestRunSearchBenchmarks(t *testing.T) {
+	maps, err := GeneratedBenchMaps(16, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	RunSearchBenchmarks(&out, maps, BenchOptions{MinTime: time.Millisecond})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if want := 1 + len(maps)*len(algorithmNames)*len(QueueKinds()); len(lines) != want {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), want, out.String())
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 8 {
+			t.Fatalf("malformed row %q", line)
+		}
+		if fields[2] == "binary" && fields[7] != "0" {
+			t.Errorf("binary heap differs from itself: %q", line)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"container/heap"
+	"math"
+	"math/bits"
+)
+
+// queueEntry - запись в очередях с ленивым удалением.
+// Запись устарела, если узел уже извлечен или его FCost с тех пор изменился.
+type queueEntry struct {
+	node *Node
+	f    float64
+	key  uint64
+}
+
+func (e queueEntry) stale() bool {
+	return e.node.Index < 0 || e.node.FCost != e.f
+}
+
+// quantize переводит FCost в целочисленный ключ с шагом 1/resolution;
+// ключи больше MaxUint64 насыщаются
+func quantize(f, resolution float64) uint64 {
+	if !(f > 0) {
+		return 0
+	}
+	v := math.Floor(f*resolution + 1e-9)
+	if v >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(v)
+}
+
+// entryHeap - двоичная куча записей по FCost для container/heap: точный
+// порядок для ключей, которые не помещаются в корзины или насыщены
+type entryHeap []queueEntry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].f < h[j].f }
+func (h entryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x any)        { *h = append(*h, x.(queueEntry)) }
+func (h *entryHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// popLive извлекает узел с наименьшим FCost, пропуская устаревшие записи
+func (h *entryHeap) popLive() *Node {
+	for h.Len() > 0 {
+		e := heap.Pop(h).(queueEntry)
+		if e.stale() {
+			continue
+		}
+		e.node.Index = -1
+		return e.node
+	}
+	return nil
+}
+
+// bucketQueueKeys - сколько корзин BucketQueue держит массивом; записи с
+// большими ключами хранятся в куче, чтобы огромные стоимости не
+// раздували массив
+const bucketQueueKeys = 1 << 16
+
+// BucketQueue - очередь Дейкстры-Дайала: массив корзин по целому ключу.
+// Для целочисленных стоимостей порядок точный; для дробных узлы внутри
+// одной корзины не упорядочены, и путь может оказаться длиннее оптимального
+// не более чем на 1/resolution. Ключи от bucketQueueKeys и больше
+// упорядочивает двоичная куча, она извлекается после всех корзин.
+// Update добавляет новую запись, старая пропускается при извлечении.
+type BucketQueue struct {
+	resolution float64
+	buckets    [][]queueEntry
+	overflow   entryHeap // записи с ключами вне массива корзин
+	cursor     int       // нижняя граница индекса непустой корзины
+	top        int       // верхняя граница использованных корзин
+	size       int
+}
+
+func NewBucketQueue(resolution float64) *BucketQueue {
+	return &BucketQueue{resolution: resolution}
+}
+
+func (q *BucketQueue) Len() int {
+	return q.size
+}
+
+func (q *BucketQueue) Push(node *Node) {
+	node.Index = 0
+	q.insert(node)
+	q.size++
+}
+
+func (q *BucketQueue) Update(node *Node) {
+	q.insert(node)
+}
+
+func (q *BucketQueue) insert(node *Node) {
+	k := quantize(node.FCost, q.resolution)
+	if k >= bucketQueueKeys {
+		heap.Push(&q.overflow, queueEntry{node: node, f: node.FCost, key: k})
+		return
+	}
+	key := int(k)
+	for key >= len(q.buckets) {
+		q.buckets = append(q.buckets, nil)
+	}
+	q.buckets[key] = append(q.buckets[key], queueEntry{node: node, f: node.FCost})
+	if key < q.cursor {
+		q.cursor = key
+	}
+	if key > q.top {
+		q.top = key
+	}
+}
+
+func (q *BucketQueue) Pop() *Node {
+	for ; q.cursor < len(q.buckets); q.cursor++ {
+		bucket := q.buckets[q.cursor]
+		for len(bucket) > 0 {
+			// LIFO внутри корзины: при равном f раньше раскрываются свежие узлы
+			e := bucket[len(bucket)-1]
+			bucket = bucket[:len(bucket)-1]
+			if e.stale() {
+				continue
+			}
+			q.buckets[q.cursor] = bucket
+			q.size--
+			e.node.Index = -1
+			return e.node
+		}
+		q.buckets[q.cursor] = bucket
+	}
+	if n := q.overflow.popLive(); n != nil {
+		q.size--
+		return n
+	}
+	return nil
+}
+
+func (q *BucketQueue) Reset() {
+	for i := 0; i <= q.top && i < len(q.buckets); i++ {
+		q.buckets[i] = q.buckets[i][:0]
+	}
+	q.overflow = q.overflow[:0]
+	q.cursor, q.top, q.size = 0, 0, 0
+}
+
+// ===================================================== //
+// Радиксная куча (radix heap) //
+
+// RadixHeap - монотонная очередь с целочисленными ключами.
+// Корзина i содержит ключи, отличающиеся от последнего извлеченного
+// старшим битом номер i. Ключ меньше последнего извлеченного
+// (возможен только из-за округления) приравнивается к нему. Насыщенные
+// ключи (FCost*resolution от 2^64) упорядочивает двоичная куча, она
+// извлекается после всех корзин.
+type RadixHeap struct {
+	resolution float64
+	buckets    [65][]queueEntry
+	overflow   entryHeap
+	last       uint64
+	size       int
+}
+
+func NewRadixHeap(resolution float64) *RadixHeap {
+	return &RadixHeap{resolution: resolution}
+}
+
+func (q *RadixHeap) Len() int {
+	return q.size
+}
+
+func (q *RadixHeap) Push(node *Node) {
+	node.Index = 0
+	q.insert(node)
+	q.size++
+}
+
+func (q *RadixHeap) Update(node *Node) {
+	q.insert(node)
+}
+
+func (q *RadixHeap) insert(node *Node) {
+	key := quantize(node.FCost, q.resolution)
+	if key == math.MaxUint64 {
+		heap.Push(&q.overflow, queueEntry{node: node, f: node.FCost, key: key})
+		return
+	}
+	if key < q.last {
+		key = q.last
+	}
+	q.put(queueEntry{node: node, f: node.FCost, key: key})
+}
+
+func (q *RadixHeap) put(e queueEntry) {
+	b := bits.Len64(e.key ^ q.last)
+	q.buckets[b] = append(q.buckets[b], e)
+}
+
+func (q *RadixHeap) Pop() *Node {
+	for {
+		if len(q.buckets[0]) == 0 {
+			// Находим первую непустую корзину и перераспределяем ее
+			i := 1
+			for i < len(q.buckets) && len(q.buckets[i]) == 0 {
+				i++
+			}
+			if i == len(q.buckets) {
+				n := q.overflow.popLive()
+				if n != nil {
+					q.size--
+				}
+				return n
+			}
+			bucket := q.buckets[i]
+			min := bucket[0].key
+			for _, e := range bucket[1:] {
+				if e.key < min {
+					min = e.key
+				}
+			}
+			q.last = min
+			q.buckets[i] = bucket[:0]
+			for _, e := range bucket {
+				if !e.stale() {
+					q.put(e)
+				}
+			}
+			continue
+		}
+
+		b := q.buckets[0]
+		e := b[len(b)-1]
+		q.buckets[0] = b[:len(b)-1]
+		if e.stale() {
+			continue
+		}
+		q.size--
+		e.node.Index = -1
+		return e.node
+	}
+}
+
+func (q *RadixHeap) Reset() {
+	for i := range q.buckets {
+		q.buckets[i] = q.buckets[i][:0]
+	}
+	q.overflow = q.overflow[:0]
+	q.last, q.size = 0, 0
+}
@@ -2,279 +2,606 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"image/color"
+	"math"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/palette"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
 )
 
-// ... (все предыдущие структуры Node, OpenList, ClosedList, Grid остаются без изменений)
+// CellState - категория клетки при отрисовке
+type CellState uint8
 
-// GridData представляет данные для отображения сетки в виде тепловой карты
+const (
+	CellFree CellState = iota
+	CellObstacle
+	CellPath
+	CellOpen
+	CellClosed
+	CellStart
+	CellGoal
+	cellStates // число категорий; дальше идут уровни градиента рельефа
+)
+
+// terrainLevels - число оттенков градиента рельефа
+const terrainLevels = 8
+
+// rasterizeCells - размер сетки, начиная с которого PlotGrid рисует
+// тепловую карту растром
+const rasterizeCells = 128 * 128
+
+// valueLevels - число оттенков тепловой карты поиска по умолчанию
+const valueLevels = 32
+
+// CellPalette - цвета категорий клеток. Нулевые поля заменяются
+// цветами DefaultCellPalette.
+type CellPalette struct {
+	Free, Obstacle, Path, Open, Closed, Start, Goal color.Color
+	// Line - цвет линии пути поверх клеток
+	Line color.Color
+	// TerrainLow и TerrainHigh - концы градиента для свободных клеток
+	// со стоимостью больше 1
+	TerrainLow, TerrainHigh color.Color
+	// Values - шкала тепловой карты стоимостей и порядка раскрытия
+	Values palette.Palette
+}
+
+// DefaultCellPalette возвращает палитру по умолчанию
+func DefaultCellPalette() CellPalette {
+	return CellPalette{
+		Free:        color.RGBA{255, 255, 255, 255},
+		Obstacle:    color.RGBA{0, 0, 0, 255},
+		Path:        color.RGBA{128, 128, 128, 255},
+		Open:        color.RGBA{170, 225, 170, 255},
+		Closed:      color.RGBA{175, 200, 240, 255},
+		Start:       color.RGBA{0, 255, 0, 255},
+		Goal:        color.RGBA{255, 0, 0, 255},
+		Line:        color.RGBA{0, 0, 255, 255},
+		TerrainLow:  color.RGBA{235, 225, 195, 255},
+		TerrainHigh: color.RGBA{130, 90, 50, 255},
+		Values:      palette.Heat(valueLevels, 1),
+	}
+}
+
+func (p CellPalette) withDefaults() CellPalette {
+	d := DefaultCellPalette()
+	for _, f := range []struct {
+		dst *color.Color
+		def color.Color
+	}{
+		{&p.Free, d.Free}, {&p.Obstacle, d.Obstacle}, {&p.Path, d.Path},
+		{&p.Open, d.Open}, {&p.Closed, d.Closed}, {&p.Start, d.Start},
+		{&p.Goal, d.Goal}, {&p.Line, d.Line},
+		{&p.TerrainLow, d.TerrainLow}, {&p.TerrainHigh, d.TerrainHigh},
+	} {
+		if *f.dst == nil {
+			*f.dst = f.def
+		}
+	}
+	if p.Values == nil {
+		p.Values = d.Values
+	}
+	return p
+}
+
+// Colors возвращает цвета в порядке CellState, за которыми следуют
+// terrainLevels оттенков рельефа и шкала Values; реализует palette.Palette
+func (p CellPalette) Colors() []color.Color {
+	colors := []color.Color{p.Free, p.Obstacle, p.Path, p.Open, p.Closed, p.Start, p.Goal}
+	for i := 0; i < terrainLevels; i++ {
+		colors = append(colors, blend(p.TerrainLow, p.TerrainHigh, float64(i)/(terrainLevels-1)))
+	}
+	if p.Values != nil {
+		colors = append(colors, p.Values.Colors()...)
+	}
+	return colors
+}
+
+// blend смешивает цвета a и b в пропорции t
+func blend(a, b color.Color, t float64) color.Color {
+	ca := color.RGBAModel.Convert(a).(color.RGBA)
+	cb := color.RGBAModel.Convert(b).(color.RGBA)
+	mix := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + t*(float64(y)-float64(x)))) }
+	return color.RGBA{mix(ca.R, cb.R), mix(ca.G, cb.G), mix(ca.B, cb.B), mix(ca.A, cb.A)}
+}
+
+// Overlay - способ наложения снимка поиска на карту
+type Overlay uint8
+
+const (
+	OverlaySets  Overlay = iota // открытый и закрытый списки цветами палитры
+	OverlayGCost                // тепловая карта стоимости от старта
+	OverlayOrder                // тепловая карта порядка раскрытия
+)
+
+var overlayNames = [...]string{
+	OverlaySets:  "sets",
+	OverlayGCost: "g",
+	OverlayOrder: "order",
+}
+
+func (o Overlay) String() string {
+	if int(o) < len(overlayNames) {
+		return overlayNames[o]
+	}
+	return fmt.Sprintf("Overlay(%d)", o)
+}
+
+// ParseOverlay разбирает имя наложения: sets, g, order
+func ParseOverlay(name string) (Overlay, error) {
+	for o, n := range overlayNames {
+		if n == name {
+			return Overlay(o), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overlay %q (want sets, g or order)", name)
+}
+
+// RenderOptions - общие параметры PlotGrid и PlotGridDetailed.
+// Нулевое значение дает отображение по умолчанию.
+type RenderOptions struct {
+	Title string
+	// Width и Height - размер изображения; 0 - квадрат по умолчанию
+	Width, Height vg.Length
+	Palette       CellPalette
+	// Start и Goal отмечаются маркерами; nil - концы пути
+	Start, Goal *Point
+	// Open и Closed - открытый и закрытый списки поиска для наложения;
+	// если оба пусты, берутся из Trace
+	Open, Closed []Point
+	// Trace - снимок поиска для тепловых карт Overlay
+	Trace   *SearchTrace
+	Overlay Overlay
+	// HideGrid отключает линии координатной сетки
+	HideGrid bool
+	// LabelWaypoints подписывает координаты концов и поворотов пути
+	// в векторном выводе SaveGridVector
+	LabelWaypoints bool
+}
+
+func (o RenderOptions) withDefaults(title string, size vg.Length, path []*Node) RenderOptions {
+	if o.Title == "" {
+		o.Title = title
+	}
+	if o.Width == 0 {
+		o.Width = size
+	}
+	if o.Height == 0 {
+		o.Height = size
+	}
+	o.Palette = o.Palette.withDefaults()
+	if o.Trace != nil && len(o.Open) == 0 && len(o.Closed) == 0 {
+		o.Open, o.Closed = o.Trace.Open, o.Trace.Closed
+	}
+	if len(path) > 0 {
+		if o.Start == nil {
+			o.Start = &path[0].Position
+		}
+		if o.Goal == nil {
+			o.Goal = &path[len(path)-1].Position
+		}
+	}
+	return o
+}
+
+// terrainRange возвращает диапазон стоимостей свободных клеток дороже 1
+func terrainRange(grid *Grid) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, c := range grid.Costs {
+		if c > 1 && !math.IsInf(c, 1) {
+			lo, hi = math.Min(lo, c), math.Max(hi, c)
+		}
+	}
+	return lo, hi
+}
+
+// terrainLevel возвращает номер оттенка рельефа для стоимости cost
+func terrainLevel(cost, lo, hi float64) int {
+	if hi <= lo {
+		return terrainLevels - 1
+	}
+	return int(math.Round((cost - lo) / (hi - lo) * (terrainLevels - 1)))
+}
+
+// values возвращает значения тепловой карты поиска по клеткам и их
+// диапазон; nil, если наложение не требует значений
+func (o RenderOptions) values() (values []float64, lo, hi float64) {
+	if o.Trace == nil || o.Overlay == OverlaySets {
+		return nil, 0, 0
+	}
+	values = make([]float64, len(o.Trace.GCost))
+	for i := range values {
+		switch {
+		case o.Overlay == OverlayGCost:
+			values[i] = o.Trace.GCost[i]
+		case o.Trace.Order[i] >= 0:
+			values[i] = float64(o.Trace.Order[i])
+		default:
+			values[i] = math.NaN()
+		}
+	}
+
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	return values, lo, hi
+}
+
+// GridData представляет данные для отображения сетки в виде тепловой карты.
+// Номера цветов всех клеток вычисляются один раз при создании.
 type GridData struct {
-    grid *Grid
-    path []*Node
+	grid  *Grid
+	cells []uint16 // номер цвета палитры по клеткам, y*Width+x
+
+	valued     bool // есть тепловая карта поиска
+	vlo, vhi   float64
+	valueBase  int // номер первого цвета шкалы в палитре
+	valueCount int // число цветов шкалы
+}
+
+// newGridData растеризует состояние клеток слоями: рельеф и препятствия,
+// списки поиска или тепловая карта, путь, старт и цель
+func newGridData(grid *Grid, path []*Node, opts RenderOptions) GridData {
+	gd := GridData{
+		grid:       grid,
+		cells:      make([]uint16, grid.Width*grid.Height),
+		valueBase:  int(cellStates) + terrainLevels,
+		valueCount: len(opts.Palette.Values.Colors()),
+	}
+
+	lo, hi := terrainRange(grid)
+	for i, blocked := range grid.Obstacles {
+		switch {
+		case blocked:
+			gd.cells[i] = uint16(CellObstacle)
+		case grid.Costs != nil && grid.Costs[i] > 1:
+			gd.cells[i] = uint16(int(cellStates) + terrainLevel(grid.Costs[i], lo, hi))
+		}
+	}
+
+	mark := func(p Point, s CellState) {
+		if grid.InBounds(p) && !grid.Obstacles[grid.index(p)] {
+			gd.cells[grid.index(p)] = uint16(s)
+		}
+	}
+	for _, p := range opts.Closed {
+		mark(p, CellClosed)
+	}
+	for _, p := range opts.Open {
+		mark(p, CellOpen)
+	}
+
+	// Тепловая карта закрывает списки там, где у клетки есть значение
+	values, vlo, vhi := opts.values()
+	if values != nil && gd.valueCount > 0 {
+		gd.valued, gd.vlo, gd.vhi = true, vlo, vhi
+		for i, v := range values {
+			if !math.IsNaN(v) && !grid.Obstacles[i] {
+				gd.cells[i] = uint16(gd.valueBase + gd.valueLevel(v))
+			}
+		}
+	}
+
+	for _, node := range path {
+		mark(node.Position, CellPath)
+	}
+	// Старт и цель рисуются поверх пути
+	if opts.Start != nil {
+		mark(*opts.Start, CellStart)
+	}
+	if opts.Goal != nil {
+		mark(*opts.Goal, CellGoal)
+	}
+	return gd
 }
 
 // Dims возвращает размеры сетки для HeatMap
 func (gd GridData) Dims() (c, r int) {
-    return gd.grid.Width, gd.grid.Height
+	return gd.grid.Width, gd.grid.Height
 }
 
-// Z возвращает значение для каждой ячейки сетки
+// Z возвращает номер цвета палитры для каждой ячейки сетки
 func (gd GridData) Z(c, r int) float64 {
-    // Инвертируем Y координату для правильного отображения
-    y := gd.grid.Height - 1 - r
-    
-    // Проверяем, является ли клетка частью пути
-    for _, node := range gd.path {
-        if node.Position.x == c && node.Position.y == y {
-            return 0.5 // Путь - серый цвет
-        }
-    }
-    
-    // Проверяем препятствия
-    if gd.grid.IsObstacle(Point{c, y}) {
-        return 1.0 // Препятствие - черный цвет
-    }
-    
-    return 0.0 // Свободная клетка - белый цвет
+	// Инвертируем Y координату для правильного отображения
+	return float64(gd.colorIndex(Point{c, gd.grid.Height - 1 - r}))
+}
+
+// colorIndex возвращает номер цвета палитры для клетки
+func (gd GridData) colorIndex(p Point) int {
+	return int(gd.cells[gd.grid.index(p)])
+}
+
+// raster переводит номера цветов клеток в изображение, клетка на пиксель
+func (gd GridData) raster(colors []color.Color) *image.RGBA {
+	rgba := make([]color.RGBA, len(colors))
+	for i, c := range colors {
+		rgba[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, gd.grid.Width, gd.grid.Height))
+	for i, idx := range gd.cells {
+		c, px := rgba[idx], img.Pix[i*4:i*4+4]
+		px[0], px[1], px[2], px[3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+// valueLevel возвращает номер оттенка шкалы для значения v
+func (gd GridData) valueLevel(v float64) int {
+	if gd.vhi <= gd.vlo {
+		return 0
+	}
+	return int(math.Round((v - gd.vlo) / (gd.vhi - gd.vlo) * float64(gd.valueCount-1)))
+}
+
+// valueLegend добавляет в легенду концы шкалы тепловой карты поиска
+func (gd GridData) valueLegend(p *plot.Plot, opts RenderOptions) {
+	if !gd.valued || math.IsInf(gd.vlo, 1) {
+		return
+	}
+	colors := opts.Palette.Values.Colors()
+	p.Legend.Add(fmt.Sprintf("%s = %.4g", opts.Overlay, gd.vlo), legendSwatch{colors[0]})
+	p.Legend.Add(fmt.Sprintf("%s = %.4g", opts.Overlay, gd.vhi), legendSwatch{colors[len(colors)-1]})
 }
 
 // X возвращает X координату для ячейки
 func (gd GridData) X(c int) float64 {
-    return float64(c)
+	return float64(c)
 }
 
 // Y возвращает Y координату для ячейки
 func (gd GridData) Y(r int) float64 {
-    return float64(r)
+	return float64(r)
+}
+
+// hasObstacles сообщает, есть ли на сетке препятствия
+func (gd GridData) hasObstacles() bool {
+	for _, blocked := range gd.grid.Obstacles {
+		if blocked {
+			return true
+		}
+	}
+	return false
+}
+
+// legendSwatch - квадрат цвета категории для легенды
+type legendSwatch struct{ color color.Color }
+
+func (s legendSwatch) Thumbnail(c *draw.Canvas) {
+	c.FillPolygon(s.color, c.ClipPolygonXY([]vg.Point{
+		{X: c.Min.X, Y: c.Min.Y}, {X: c.Max.X, Y: c.Min.Y},
+		{X: c.Max.X, Y: c.Max.Y}, {X: c.Min.X, Y: c.Max.Y},
+	}))
+}
+
+// endpointMarkers добавляет маркеры старта и цели
+func endpointMarkers(p *plot.Plot, grid *Grid, opts RenderOptions, radius vg.Length) error {
+	markers := []struct {
+		point *Point
+		color color.Color
+		shape int
+		label string
+	}{
+		{opts.Start, opts.Palette.Start, 1, "Start"},
+		{opts.Goal, opts.Palette.Goal, 2, "Goal"},
+	}
+	for _, m := range markers {
+		if m.point == nil {
+			continue
+		}
+		scatter, err := plotter.NewScatter(plotter.XYs{{
+			X: float64(m.point.x),
+			Y: float64(grid.Height - 1 - m.point.y), // Инвертируем Y
+		}})
+		if err != nil {
+			return err
+		}
+		scatter.GlyphStyle.Color = m.color
+		scatter.GlyphStyle.Radius = radius
+		scatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[m.shape]
+		p.Add(scatter)
+		p.Legend.Add(m.label, scatter)
+	}
+	return nil
+}
+
+// pathLine строит линию через центры клеток пути
+func pathLine(grid *Grid, path []*Node) (*plotter.Line, error) {
+	points := make(plotter.XYs, len(path))
+	for i, node := range path {
+		points[i].X = float64(node.Position.x)
+		points[i].Y = float64(grid.Height - 1 - node.Position.y) // Инвертируем Y
+	}
+	return plotter.NewLine(points)
+}
+
+// newGridPlot создает график с осями по размерам сетки
+func newGridPlot(grid *Grid, title string) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = "X Coordinate"
+	p.Y.Label.Text = "Y Coordinate"
+
+	// Настройка размера графика
+	p.X.Min = -0.5
+	p.X.Max = float64(grid.Width) - 0.5
+	p.Y.Min = -0.5
+	p.Y.Max = float64(grid.Height) - 0.5
+	return p
 }
 
 // PlotGrid создает графическое отображение сетки с найденным путем
-func PlotGrid(grid *Grid, path []*Node, filename string) error {
-    // Создаем новый график
-    p := plot.New()
-    p.Title.Text = "A* Pathfinding Visualization"
-    p.X.Label.Text = "X Coordinate"
-    p.Y.Label.Text = "Y Coordinate"
-    
-    // Настройка размера графика
-    p.X.Min = -0.5
-    p.X.Max = float64(grid.Width) - 0.5
-    p.Y.Min = -0.5
-    p.Y.Max = float64(grid.Height) - 0.5
-    
-    // Создаем тепловую карту для основы сетки
-    gridData := GridData{grid: grid, path: path}
-    hm := plotter.NewHeatMap(gridData, nil)
-    
-    // Настройка цветовой схемы
-    colors := []color.Color{
-        color.RGBA{255, 255, 255, 255}, // Белый для свободных клеток
-        color.RGBA{128, 128, 128, 255}, // Серый для пути
-        color.RGBA{0, 0, 0, 255},       // Черный для препятствий
-    }
-    hm.Palette = palette.Heat(len(colors), 1.0)
-    
-    p.Add(hm)
-    
-    // Добавляем линию пути если он существует
-    if len(path) > 0 {
-        pathPoints := make(plotter.XYs, len(path))
-        for i, node := range path {
-            pathPoints[i].X = float64(node.Position.x)
-            pathPoints[i].Y = float64(grid.Height - 1 - node.Position.y) // Инвертируем Y
-        }
-        
-        line, err := plotter.NewLine(pathPoints)
-        if err != nil {
-            return err
-        }
-        line.Color = color.RGBA{0, 0, 255, 255} // Синий цвет для пути
-        line.Width = vg.Points(3)
-        p.Add(line)
-        
-        // Добавляем маркер старта
-        startPoint := plotter.XYs{{
-            X: float64(path[0].Position.x),
-            Y: float64(grid.Height - 1 - path[0].Position.y),
-        }}
-        startScatter, err := plotter.NewScatter(startPoint)
-        if err != nil {
-            return err
-        }
-        startScatter.GlyphStyle.Color = color.RGBA{0, 255, 0, 255} // Зеленый для старта
-        startScatter.GlyphStyle.Radius = vg.Points(8)
-        startScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[1]
-        p.Add(startScatter)
-        
-        // Добавляем маркер цели
-        goalPoint := plotter.XYs{{
-            X: float64(path[len(path)-1].Position.x),
-            Y: float64(grid.Height - 1 - path[len(path)-1].Position.y),
-        }}
-        goalScatter, err := plotter.NewScatter(goalPoint)
-        if err != nil {
-            return err
-        }
-        goalScatter.GlyphStyle.Color = color.RGBA{255, 0, 0, 255} // Красный для цели
-        goalScatter.GlyphStyle.Radius = vg.Points(8)
-        goalScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[2]
-        p.Add(goalScatter)
-        
-        // Добавляем легенду
-        p.Legend.Add("Path", line)
-        p.Legend.Add("Start", startScatter)
-        p.Legend.Add("Goal", goalScatter)
-        p.Legend.Top = true
-    }
-    
-    // Добавляем сетку для лучшей видимости
-    p.Add(plotter.NewGrid())
-    
-    // Сохраняем график
-    return p.Save(8*vg.Inch, 8*vg.Inch, filename)
+func PlotGrid(grid *Grid, path []*Node, filename string, opts RenderOptions) error {
+	opts = opts.withDefaults("A* Pathfinding Visualization", 8*vg.Inch, path)
+	p, err := gridPlot(grid, path, opts)
+	if err != nil {
+		return err
+	}
+
+	// Сохраняем график
+	return p.Save(opts.Width, opts.Height, filename)
+}
+
+// gridPlot строит график PlotGrid; opts должны быть дополнены withDefaults
+func gridPlot(grid *Grid, path []*Node, opts RenderOptions) (*plot.Plot, error) {
+	p := newGridPlot(grid, opts.Title)
+
+	// Тепловая карта с номерами цветов категориальной палитры
+	gridData := newGridData(grid, path, opts)
+	if grid.Width*grid.Height > rasterizeCells {
+		// Большие сетки рисуются одним растром вместо многоугольника на клетку
+		img := gridData.raster(opts.Palette.Colors())
+		p.Add(plotter.NewImage(img, -0.5, -0.5, float64(grid.Width)-0.5, float64(grid.Height)-0.5))
+	} else {
+		hm := plotter.NewHeatMap(gridData, opts.Palette)
+		hm.Min = 0
+		hm.Max = float64(len(opts.Palette.Colors()) - 1)
+		p.Add(hm)
+	}
+
+	if gridData.hasObstacles() {
+		p.Legend.Add("Obstacles", legendSwatch{opts.Palette.Obstacle})
+	}
+	// Тепловая карта g закрывает оба списка, карта порядка - только закрытый
+	if len(opts.Open) > 0 && (!gridData.valued || opts.Overlay == OverlayOrder) {
+		p.Legend.Add("Open", legendSwatch{opts.Palette.Open})
+	}
+	if len(opts.Closed) > 0 && !gridData.valued {
+		p.Legend.Add("Closed", legendSwatch{opts.Palette.Closed})
+	}
+	gridData.valueLegend(p, opts)
+
+	// Добавляем линию пути если он существует
+	if len(path) > 0 {
+		line, err := pathLine(grid, path)
+		if err != nil {
+			return nil, err
+		}
+		line.Color = opts.Palette.Line
+		line.Width = vg.Points(3)
+		p.Add(line)
+		p.Legend.Add("Path", line)
+	}
+	if err := endpointMarkers(p, grid, opts, vg.Points(8)); err != nil {
+		return nil, err
+	}
+	p.Legend.Top = true
+
+	// Добавляем сетку для лучшей видимости
+	if !opts.HideGrid {
+		p.Add(plotter.NewGrid())
+	}
+	return p, nil
 }
 
 // Альтернативная версия с более детальным отображением
-func PlotGridDetailed(grid *Grid, path []*Node, filename string) error {
-    p := plot.New()
-    p.Title.Text = "A* Pathfinding - Detailed View"
-    p.X.Label.Text = "X Coordinate"
-    p.Y.Label.Text = "Y Coordinate"
-    
-    // Настройка размера графика
-    p.X.Min = -0.5
-    p.X.Max = float64(grid.Width) - 0.5
-    p.Y.Min = -0.5
-    p.Y.Max = float64(grid.Height) - 0.5
-    
-    // Создаем отдельные scatter plots для разных типов клеток
-    var obstacles, freeCells, pathCells plotter.XYs
-    
-    // Создаем карту пути для быстрого поиска
-    pathMap := make(map[string]bool)
-    for _, node := range path {
-        key := fmt.Sprintf("%d,%d", node.Position.x, node.Position.y)
-        pathMap[key] = true
-    }
-    
-    // Заполняем точки для каждого типа клеток
-    for x := 0; x < grid.Width; x++ {
-        for y := 0; y < grid.Height; y++ {
-            plotY := float64(grid.Height - 1 - y) // Инвертируем Y
-            key := fmt.Sprintf("%d,%d", x, y)
-            
-            if pathMap[key] {
-                pathCells = append(pathCells, plotter.XY{
-                    X: float64(x),
-                    Y: plotY,
-                })
-            } else if grid.IsObstacle(Point{x, y}) {
-                obstacles = append(obstacles, plotter.XY{
-                    X: float64(x),
-                    Y: plotY,
-                })
-            } else {
-                freeCells = append(freeCells, plotter.XY{
-                    X: float64(x),
-                    Y: plotY,
-                })
-            }
-        }
-    }
-    
-    // Добавляем свободные клетки
-    if len(freeCells) > 0 {
-        freeScatter, err := plotter.NewScatter(freeCells)
-        if err != nil {
-            return err
-        }
-        freeScatter.GlyphStyle.Color = color.RGBA{240, 240, 240, 255}
-        freeScatter.GlyphStyle.Radius = vg.Points(15)
-        freeScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[5]
-        p.Add(freeScatter)
-    }
-    
-    // Добавляем препятствия
-    if len(obstacles) > 0 {
-        obstacleScatter, err := plotter.NewScatter(obstacles)
-        if err != nil {
-            return err
-        }
-        obstacleScatter.GlyphStyle.Color = color.RGBA{0, 0, 0, 255}
-        obstacleScatter.GlyphStyle.Radius = vg.Points(20)
-        obstacleScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[4]
-        p.Add(obstacleScatter)
-        p.Legend.Add("Obstacles", obstacleScatter)
-    }
-    
-    // Добавляем путь
-    if len(pathCells) > 0 {
-        pathScatter, err := plotter.NewScatter(pathCells)
-        if err != nil {
-            return err
-        }
-        pathScatter.GlyphStyle.Color = color.RGBA{0, 0, 255, 200}
-        pathScatter.GlyphStyle.Radius = vg.Points(12)
-        pathScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[0]
-        p.Add(pathScatter)
-        p.Legend.Add("Path", pathScatter)
-    }
-    
-    // Добавляем соединяющую линию для пути
-    if len(path) > 0 {
-        pathLine := make(plotter.XYs, len(path))
-        for i, node := range path {
-            pathLine[i].X = float64(node.Position.x)
-            pathLine[i].Y = float64(grid.Height - 1 - node.Position.y)
-        }
-        
-        line, err := plotter.NewLine(pathLine)
-        if err != nil {
-            return err
-        }
-        line.Color = color.RGBA{0, 0, 255, 150}
-        line.Width = vg.Points(2)
-        p.Add(line)
-        
-        // Специальные маркеры для старта и цели
-        startPoint := plotter.XYs{{
-            X: float64(path[0].Position.x),
-            Y: float64(grid.Height - 1 - path[0].Position.y),
-        }}
-        startScatter, err := plotter.NewScatter(startPoint)
-        if err != nil {
-            return err
-        }
-        startScatter.GlyphStyle.Color = color.RGBA{0, 255, 0, 255}
-        startScatter.GlyphStyle.Radius = vg.Points(15)
-        startScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[1]
-        p.Add(startScatter)
-        p.Legend.Add("Start", startScatter)
-        
-        goalPoint := plotter.XYs{{
-            X: float64(path[len(path)-1].Position.x),
-            Y: float64(grid.Height - 1 - path[len(path)-1].Position.y),
-        }}
-        goalScatter, err := plotter.NewScatter(goalPoint)
-        if err != nil {
-            return err
-        }
-        goalScatter.GlyphStyle.Color = color.RGBA{255, 0, 0, 255}
-        goalScatter.GlyphStyle.Radius = vg.Points(15)
-        goalScatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[2]
-        p.Add(goalScatter)
-        p.Legend.Add("Goal", goalScatter)
-    }
-    
-    // Добавляем сетку
-    p.Add(plotter.NewGrid())
-    p.Legend.Top = true
-    
-    return p.Save(10*vg.Inch, 10*vg.Inch, filename)
-}
\ No newline at end of file
+func PlotGridDetailed(grid *Grid, path []*Node, filename string, opts RenderOptions) error {
+	opts = opts.withDefaults("A* Pathfinding - Detailed View", 10*vg.Inch, path)
+	p := newGridPlot(grid, opts.Title)
+
+	// Слои scatter plots для разных типов клеток; цвет задается
+	// для каждой клетки, чтобы показать рельеф и тепловую карту поиска
+	type layer struct {
+		cells  plotter.XYs
+		colors []color.Color
+		radius vg.Length
+		shape  int
+		label  string
+	}
+	const (
+		freeLayer = iota
+		closedLayer
+		openLayer
+		valueLayer
+		obstacleLayer
+		pathLayer
+	)
+	layers := []layer{
+		freeLayer:     {radius: vg.Points(15), shape: 5},
+		closedLayer:   {radius: vg.Points(15), shape: 5, label: "Closed"},
+		openLayer:     {radius: vg.Points(15), shape: 5, label: "Open"},
+		valueLayer:    {radius: vg.Points(15), shape: 5},
+		obstacleLayer: {radius: vg.Points(20), shape: 6, label: "Obstacles"},
+		pathLayer:     {radius: vg.Points(12), shape: 5, label: "Path"},
+	}
+
+	gridData := newGridData(grid, path, opts)
+	colors := opts.Palette.Colors()
+
+	// Заполняем точки для каждого типа клеток
+	for x := 0; x < grid.Width; x++ {
+		for y := 0; y < grid.Height; y++ {
+			idx := gridData.colorIndex(Point{x, y})
+			l := freeLayer
+			switch {
+			case idx == int(CellPath):
+				l = pathLayer
+			case idx == int(CellObstacle):
+				l = obstacleLayer
+			case idx == int(CellOpen):
+				l = openLayer
+			case idx == int(CellClosed):
+				l = closedLayer
+			case idx >= gridData.valueBase:
+				l = valueLayer
+			case idx == int(CellStart) || idx == int(CellGoal):
+				idx = int(CellFree) // под маркером
+			}
+			xy := plotter.XY{X: float64(x), Y: float64(grid.Height - 1 - y)} // Инвертируем Y
+			layers[l].cells = append(layers[l].cells, xy)
+			layers[l].colors = append(layers[l].colors, colors[idx])
+		}
+	}
+
+	for _, layer := range layers {
+		if len(layer.cells) == 0 {
+			continue
+		}
+		scatter, err := plotter.NewScatter(layer.cells)
+		if err != nil {
+			return err
+		}
+		scatter.GlyphStyle.Color = layer.colors[0]
+		scatter.GlyphStyle.Radius = layer.radius
+		scatter.GlyphStyle.Shape = plotutil.DefaultGlyphShapes[layer.shape]
+		style, colors := scatter.GlyphStyle, layer.colors
+		scatter.GlyphStyleFunc = func(k int) draw.GlyphStyle {
+			style.Color = colors[k]
+			return style
+		}
+		p.Add(scatter)
+		if layer.label != "" {
+			p.Legend.Add(layer.label, scatter)
+		}
+	}
+	gridData.valueLegend(p, opts)
+
+	// Добавляем соединяющую линию для пути
+	if len(path) > 0 {
+		line, err := pathLine(grid, path)
+		if err != nil {
+			return err
+		}
+		line.Color = opts.Palette.Line
+		line.Width = vg.Points(2)
+		p.Add(line)
+	}
+	if err := endpointMarkers(p, grid, opts, vg.Points(15)); err != nil {
+		return err
+	}
+
+	// Добавляем сетку
+	if !opts.HideGrid {
+		p.Add(plotter.NewGrid())
+	}
+	p.Legend.Top = true
+
+	return p.Save(opts.Width, opts.Height, filename)
+}
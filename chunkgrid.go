@@ -0,0 +1,263 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// ChunkProvider заполняет чанк (cx, cy) неограниченной сетки: chunk -
+// пустая сетка ChunkSize x ChunkSize, клетка (x, y) чанка - это клетка
+// (cx*ChunkSize+x, cy*ChunkSize+y) мира. Провайдер должен быть
+// детерминированным: вытесненный чанк загружается заново.
+type ChunkProvider func(cx, cy int, chunk *Grid) error
+
+// ChunkedGridOptions - настройки ChunkedGrid
+type ChunkedGridOptions struct {
+	// ChunkSize - сторона чанка в клетках; 0 - 64
+	ChunkSize int
+	// MaxChunks - сколько чанков держать в памяти; 0 - 256, не меньше 4
+	MaxChunks int
+	// OnEvict вызывается перед вытеснением чанка, например чтобы
+	// сохранить правки; nil - правки вытесненных чанков теряются
+	OnEvict func(cx, cy int, chunk *Grid)
+}
+
+func (o ChunkedGridOptions) withDefaults() ChunkedGridOptions {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = 64
+	}
+	if o.MaxChunks <= 0 {
+		o.MaxChunks = 256
+	}
+	// Диагональный шаг на углу затрагивает до четырех чанков
+	o.MaxChunks = max(o.MaxChunks, 4)
+	return o
+}
+
+// ChunkStats - счетчики загрузок чанков
+type ChunkStats struct {
+	Loaded    int    // чанков в памяти
+	Loads     uint64 // вызовов провайдера
+	Evictions uint64
+}
+
+type chunkKey struct {
+	cx, cy int
+}
+
+type chunk struct {
+	key  chunkKey
+	grid *Grid
+}
+
+// ChunkedGrid - неограниченная сетка из чанков, которые загружаются
+// провайдером при первом обращении и вытесняются давно не
+// использованными, когда их больше MaxChunks. Координаты клеток могут
+// быть отрицательными. Реализует Space с той же моделью шагов, что и
+// Grid, поэтому по ней ищет обычный Searcher; для поиска, где пути может
+// не быть, задайте SetExpandLimit или контекст с ограничением времени.
+// ChunkedGrid не безопасна для одновременного использования.
+type ChunkedGrid struct {
+	opts     ChunkedGridOptions
+	provider ChunkProvider
+	lru      *list.List // *chunk, недавние - в начале
+	chunks   map[chunkKey]*list.Element
+	lastHit  *chunk // последний чанк: соседние клетки обычно в нем же
+	stats    ChunkStats
+	err      error
+}
+
+// NewChunkedGrid создает пустую неограниченную сетку над provider
+func NewChunkedGrid(provider ChunkProvider, opts ChunkedGridOptions) *ChunkedGrid {
+	return &ChunkedGrid{
+		opts:     opts.withDefaults(),
+		provider: provider,
+		lru:      list.New(),
+		chunks:   make(map[chunkKey]*list.Element),
+	}
+}
+
+// ChunkSize возвращает сторону чанка в клетках
+func (c *ChunkedGrid) ChunkSize() int {
+	return c.opts.ChunkSize
+}
+
+// ChunkOf возвращает чанк клетки и ее координаты внутри чанка
+func (c *ChunkedGrid) ChunkOf(point Point) (cx, cy int, local Point) {
+	cx, lx := floorDivMod(point.x, c.opts.ChunkSize)
+	cy, ly := floorDivMod(point.y, c.opts.ChunkSize)
+	return cx, cy, Point{lx, ly}
+}
+
+// floorDivMod делит с округлением вниз, остаток всегда неотрицателен
+func floorDivMod(a, b int) (q, r int) {
+	q, r = a/b, a%b
+	if r < 0 {
+		q--
+		r += b
+	}
+	return q, r
+}
+
+// cell возвращает сетку чанка клетки, загружая его при необходимости,
+// и координаты клетки в ней
+func (c *ChunkedGrid) cell(point Point) (*Grid, Point) {
+	cx, cy, local := c.ChunkOf(point)
+	key := chunkKey{cx, cy}
+	// Последний чанк уже в начале списка
+	if c.lastHit != nil && c.lastHit.key == key {
+		return c.lastHit.grid, local
+	}
+	el, ok := c.chunks[key]
+	if ok {
+		c.lru.MoveToFront(el)
+	} else {
+		el = c.load(key)
+	}
+	c.lastHit = el.Value.(*chunk)
+	return c.lastHit.grid, local
+}
+
+// load вызывает провайдер, предварительно освобождая место. Ошибка
+// провайдера запоминается, а чанк целиком становится препятствием.
+func (c *ChunkedGrid) load(key chunkKey) *list.Element {
+	if c.lru.Len() >= c.opts.MaxChunks {
+		c.evict(c.lru.Back())
+	}
+	size := c.opts.ChunkSize
+	grid := NewGrid(size, size)
+	c.stats.Loads++
+	if err := c.provider(key.cx, key.cy, grid); err != nil {
+		if c.err == nil {
+			c.err = fmt.Errorf("chunk (%d,%d): %w", key.cx, key.cy, err)
+		}
+		grid = NewGrid(size, size)
+		grid.FillRect(Point{0, 0}, Point{size - 1, size - 1}, true)
+	} else if grid.Width != size || grid.Height != size {
+		if c.err == nil {
+			c.err = fmt.Errorf("chunk (%d,%d): provider resized it to %dx%d", key.cx, key.cy, grid.Width, grid.Height)
+		}
+		grid = NewGrid(size, size)
+		grid.FillRect(Point{0, 0}, Point{size - 1, size - 1}, true)
+	}
+	el := c.lru.PushFront(&chunk{key: key, grid: grid})
+	c.chunks[key] = el
+	return el
+}
+
+// evict вытесняет чанк, вызывая OnEvict
+func (c *ChunkedGrid) evict(el *list.Element) {
+	ch := el.Value.(*chunk)
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(ch.key.cx, ch.key.cy, ch.grid)
+	}
+	c.lru.Remove(el)
+	delete(c.chunks, ch.key)
+	if c.lastHit == ch {
+		c.lastHit = nil
+	}
+	c.stats.Evictions++
+}
+
+// Flush вытесняет все чанки, начиная с давно не использованных, вызывая
+// OnEvict для каждого
+func (c *ChunkedGrid) Flush() {
+	for c.lru.Len() > 0 {
+		c.evict(c.lru.Back())
+	}
+}
+
+// Err возвращает первую ошибку провайдера; чанки с ошибкой считаются
+// сплошным препятствием
+func (c *ChunkedGrid) Err() error {
+	return c.err
+}
+
+// Stats возвращает счетчики загрузок
+func (c *ChunkedGrid) Stats() ChunkStats {
+	stats := c.stats
+	stats.Loaded = c.lru.Len()
+	return stats
+}
+
+// IsObstacle сообщает, занята ли клетка
+func (c *ChunkedGrid) IsObstacle(point Point) bool {
+	grid, local := c.cell(point)
+	return grid.IsObstacle(local)
+}
+
+// IsValid сообщает, свободна ли клетка
+func (c *ChunkedGrid) IsValid(point Point) bool {
+	grid, local := c.cell(point)
+	return grid.IsValid(local)
+}
+
+// Cost возвращает стоимость входа в клетку
+func (c *ChunkedGrid) Cost(point Point) float64 {
+	grid, local := c.cell(point)
+	return grid.Cost(local)
+}
+
+// StepCost возвращает стоимость шага в соседнюю клетку по тем же
+// правилам, что и Grid.StepCost
+func (c *ChunkedGrid) StepCost(from, to Point) (float64, bool) {
+	grid, local := c.cell(to)
+	if !grid.IsValid(local) {
+		return 0, false
+	}
+	cost := grid.Cost(local)
+	dx, dy := to.x-from.x, to.y-from.y
+	if dx != 0 && dy != 0 {
+		if !c.IsValid(Point{from.x + dx, from.y}) || !c.IsValid(Point{from.x, from.y + dy}) {
+			return 0, false
+		}
+		return math.Sqrt2 * cost, true
+	}
+	return cost, true
+}
+
+// AddObstacle помечает клетку препятствием, загружая ее чанк
+func (c *ChunkedGrid) AddObstacle(point Point) {
+	grid, local := c.cell(point)
+	grid.AddObstacle(local)
+}
+
+// RemoveObstacle освобождает клетку, загружая ее чанк
+func (c *ChunkedGrid) RemoveObstacle(point Point) {
+	grid, local := c.cell(point)
+	grid.RemoveObstacle(local)
+}
+
+// SetCost задает стоимость входа в клетку, загружая ее чанк
+func (c *ChunkedGrid) SetCost(point Point, cost float64) {
+	grid, local := c.cell(point)
+	grid.SetCost(local, cost)
+}
+
+// TerrainChunks - провайдер бесшовного рельефа из шума Перлина, как у
+// GenerateTerrain, но без нормировки по всей карте: клетки с шумом ниже
+// water (шум примерно в [-1, 1]) - препятствия, остальные стоят от 1 до
+// maxCost с ростом высоты. scale - размер деталей в клетках.
+func TerrainChunks(seed int64, scale, water, maxCost float64) ChunkProvider {
+	noise := newPerlin(rand.New(rand.NewSource(seed)))
+	scale = max(scale, 1)
+	maxCost = max(maxCost, 1)
+	return func(cx, cy int, chunk *Grid) error {
+		x0, y0 := cx*chunk.Width, cy*chunk.Height
+		for y := 0; y < chunk.Height; y++ {
+			for x := 0; x < chunk.Width; x++ {
+				p := Point{x, y}
+				v := noise.fractal(float64(x0+x)/scale, float64(y0+y)/scale)
+				if v < water {
+					chunk.AddObstacle(p)
+					continue
+				}
+				t := min(1, (v-water)/max(1-water, 1e-9))
+				chunk.SetCost(p, math.Round((1+t*(maxCost-1))*10)/10)
+			}
+		}
+		return nil
+	}
+}
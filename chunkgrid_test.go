@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestFloorDivMod(t *testing.T) {
+	tests := []struct{ a, b, q, r int }{
+		{0, 4, 0, 0},
+		{3, 4, 0, 3},
+		{4, 4, 1, 0},
+		{-1, 4, -1, 3},
+		{-4, 4, -1, 0},
+		{-5, 4, -2, 3},
+		{-8, 4, -2, 0},
+	}
+	for _, tt := range tests {
+		if q, r := floorDivMod(tt.a, tt.b); q != tt.q || r != tt.r {
+			t.Errorf("floorDivMod(%d, %d) = %d, %d; want %d, %d", tt.a, tt.b, q, r, tt.q, tt.r)
+		}
+	}
+}
+
+func TestChunkOf(t *testing.T) {
+	c := NewChunkedGrid(func(int, int, *Grid) error { return nil }, ChunkedGridOptions{ChunkSize: 4})
+	tests := []struct {
+		p      Point
+		cx, cy int
+		local  Point
+	}{
+		{Point{0, 0}, 0, 0, Point{0, 0}},
+		{Point{3, 4}, 0, 1, Point{3, 0}},
+		{Point{-1, -1}, -1, -1, Point{3, 3}},
+		{Point{-4, 0}, -1, 0, Point{0, 0}},
+		{Point{-5, 7}, -2, 1, Point{3, 3}},
+	}
+	for _, tt := range tests {
+		cx, cy, local := c.ChunkOf(tt.p)
+		if cx != tt.cx || cy != tt.cy || local != tt.local {
+			t.Errorf("ChunkOf(%v) = %d, %d, %v; want %d, %d, %v", tt.p, cx, cy, local, tt.cx, tt.cy, tt.local)
+		}
+	}
+}
+
+func TestChunkedGridEviction(t *testing.T) {
+	var evicted []chunkKey
+	c := NewChunkedGrid(func(int, int, *Grid) error { return nil }, ChunkedGridOptions{
+		ChunkSize: 4,
+		MaxChunks: 4,
+		OnEvict:   func(cx, cy int, _ *Grid) { evicted = append(evicted, chunkKey{cx, cy}) },
+	})
+	for cx := 0; cx < 4; cx++ {
+		c.IsValid(Point{cx * 4, 0})
+	}
+	c.IsValid(Point{1, 1}) // чанк (0,0) снова недавний
+	c.IsValid(Point{16, 0})
+	c.IsValid(Point{20, 0})
+	if want := []chunkKey{{1, 0}, {2, 0}}; len(evicted) != 2 || evicted[0] != want[0] || evicted[1] != want[1] {
+		t.Errorf("evicted %v, want %v", evicted, want)
+	}
+	if s := c.Stats(); s.Loaded != 4 || s.Loads != 6 || s.Evictions != 2 {
+		t.Errorf("stats %+v, want 4 loaded, 6 loads, 2 evictions", s)
+	}
+
+	evicted = nil
+	c.Flush()
+	if want := []chunkKey{{3, 0}, {0, 0}, {4, 0}, {5, 0}}; len(evicted) != 4 || evicted[0] != want[0] || evicted[3] != want[3] {
+		t.Errorf("flush evicted %v, want %v", evicted, want)
+	}
+	if s := c.Stats(); s.Loaded != 0 || s.Evictions != 6 {
+		t.Errorf("stats after flush %+v", s)
+	}
+}
+
+func TestChunkedGridEvictedEdits(t *testing.T) {
+	blank := func(int, int, *Grid) error { return nil }
+	evictAll := func(c *ChunkedGrid) {
+		for cx := 1; cx <= 4; cx++ {
+			c.IsValid(Point{cx * 4, 0})
+		}
+	}
+
+	// Без OnEvict правки вытесненного чанка теряются
+	c := NewChunkedGrid(blank, ChunkedGridOptions{ChunkSize: 4, MaxChunks: 4})
+	c.AddObstacle(Point{1, 1})
+	c.SetCost(Point{2, 2}, 5)
+	evictAll(c)
+	if c.IsObstacle(Point{1, 1}) || c.Cost(Point{2, 2}) != 1 {
+		t.Error("edits survived eviction without OnEvict")
+	}
+
+	// OnEvict сохраняет чанк, провайдер возвращает его
+	saved := map[chunkKey]*Grid{}
+	c = NewChunkedGrid(func(cx, cy int, chunk *Grid) error {
+		if g := saved[chunkKey{cx, cy}]; g != nil {
+			copy(chunk.Obstacles, g.Obstacles)
+			chunk.Costs = append([]float64(nil), g.Costs...)
+		}
+		return nil
+	}, ChunkedGridOptions{
+		ChunkSize: 4,
+		MaxChunks: 4,
+		OnEvict:   func(cx, cy int, chunk *Grid) { saved[chunkKey{cx, cy}] = chunk },
+	})
+	c.AddObstacle(Point{1, 1})
+	c.SetCost(Point{2, 2}, 5)
+	evictAll(c)
+	if !c.IsObstacle(Point{1, 1}) || c.Cost(Point{2, 2}) != 5 {
+		t.Error("edits saved by OnEvict were not restored")
+	}
+}
+
+func TestChunkedGridProviderError(t *testing.T) {
+	errBroken := errors.New("broken chunk")
+	c := NewChunkedGrid(func(cx, cy int, chunk *Grid) error {
+		switch {
+		case cx == 1:
+			return errBroken
+		case cx == 2:
+			*chunk = *NewGrid(2, 2)
+		}
+		return nil
+	}, ChunkedGridOptions{ChunkSize: 4})
+
+	if !c.IsValid(Point{0, 0}) || c.Err() != nil {
+		t.Fatalf("healthy chunk: valid %v, error %v", c.IsValid(Point{0, 0}), c.Err())
+	}
+	for _, p := range []Point{{4, 0}, {7, 3}, {9, 1}} {
+		if !c.IsObstacle(p) {
+			t.Errorf("%v: cell of a failed chunk isn't an obstacle", p)
+		}
+	}
+	if err := c.Err(); !errors.Is(err, errBroken) || !strings.Contains(err.Error(), "chunk (1,0)") {
+		t.Errorf("Err() = %v, want the first provider error for chunk (1,0)", err)
+	}
+	if _, ok := c.StepCost(Point{3, 0}, Point{4, 0}); ok {
+		t.Error("step into a failed chunk is allowed")
+	}
+}
+
+// TestChunkedGridMatchesGrid ищет по чанкам с частым вытеснением и
+// сравнивает стоимости путей с обычной сеткой. Мир сдвинут так, что
+// часть чанков лежит в отрицательных координатах.
+func TestChunkedGridMatchesGrid(t *testing.T) {
+	const size, shift = 40, 20
+	rng := rand.New(rand.NewSource(5))
+	var evictions uint64
+	for trial := 0; trial < 10; trial++ {
+		grid := randomWeightedGrid(rng, size, size, 5)
+		start, goal := Point{rng.Intn(size), rng.Intn(size)}, Point{rng.Intn(size), rng.Intn(size)}
+		grid.RemoveObstacle(start)
+		grid.RemoveObstacle(goal)
+		provider := func(cx, cy int, chunk *Grid) error {
+			for y := 0; y < chunk.Height; y++ {
+				for x := 0; x < chunk.Width; x++ {
+					p := Point{cx*chunk.Width + x + shift, cy*chunk.Height + y + shift}
+					if !grid.IsValid(p) {
+						chunk.AddObstacle(Point{x, y})
+						continue
+					}
+					chunk.SetCost(Point{x, y}, grid.Cost(p))
+				}
+			}
+			return nil
+		}
+		for _, movement := range []Movement{Moves4, Moves8} {
+			chunks := NewChunkedGrid(provider, ChunkedGridOptions{ChunkSize: 8, MaxChunks: 4})
+			ref := NewSearcher(grid)
+			ref.SetMovement(movement)
+			want, wantErr := ref.Search(start, goal)
+
+			s := NewSearcher(chunks)
+			s.SetMovement(movement)
+			shifted := func(p Point) Point { return Point{p.x - shift, p.y - shift} }
+			got, err := s.Search(shifted(start), shifted(goal))
+			if (err == nil) != (wantErr == nil) {
+				t.Fatalf("trial %d, %v: error %v, plain grid error %v", trial, movement, err, wantErr)
+			}
+			if err == nil && got[len(got)-1].GCost != want[len(want)-1].GCost {
+				t.Errorf("trial %d, %v: cost %g, plain grid cost %g", trial, movement, got[len(got)-1].GCost, want[len(want)-1].GCost)
+			}
+			evictions += chunks.Stats().Evictions
+			if chunks.Err() != nil {
+				t.Fatal(chunks.Err())
+			}
+		}
+	}
+	if evictions == 0 {
+		t.Error("no search evicted a chunk")
+	}
+}
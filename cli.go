@@ -0,0 +1,954 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"astar/pathrpc"
+
+	"google.golang.org/grpc"
+)
+
+//go:embed scenarios/default.yaml
+var defaultScenario []byte
+
+// errUsage - ошибка в аргументах командной строки, код выхода 2
+var errUsage = errors.New("usage error")
+
+// command - подкоманда командной строки
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout io.Writer) error
+}
+
+func cliCommands() []command {
+	return []command{
+		{"solve", "найти путь на карте и вывести маршрут и статистику", cmdSolve},
+		{"render", "сохранить изображение карты с путем (PNG, SVG, PDF)", cmdRender},
+		{"animate", "записать анимацию поиска (GIF или кадры PNG)", cmdAnimate},
+		{"compare", "сравнить решатели на одной карте: панели и таблица", cmdCompare},
+		{"view", "сохранить интерактивный HTML-просмотрщик с поиском в браузере", cmdView},
+		{"generate", "сгенерировать карту", cmdGenerate},
+		{"bench", "замерить алгоритмы и очереди на картах или сценариях MovingAI", cmdBench},
+		{"validate", "проверить карту и путь", cmdValidate},
+		{"diff", "сравнить две редакции карты", cmdDiff},
+		{"serve", "запустить HTTP-сервис поиска пути", cmdServe},
+		{"run", "выполнить сценарий JSON/YAML (по умолчанию встроенный)", cmdRun},
+	}
+}
+
+// runCLI выполняет подкоманду и возвращает код выхода
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	commands := cliCommands()
+	usage := func() {
+		fmt.Fprintln(stderr, "Использование: astar <команда> [флаги] [аргументы]")
+		fmt.Fprintln(stderr, "\nКоманды:")
+		for _, c := range commands {
+			fmt.Fprintf(stderr, "  %-10s %s\n", c.name, c.summary)
+		}
+		fmt.Fprintln(stderr, "\nФлаги команды: astar <команда> -h")
+	}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage()
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(args[1:], stdout)
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp), errors.Is(err, errUsage):
+			return 2
+		default:
+			fmt.Fprintf(stderr, "Ошибка: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "Неизвестная команда %q\n\n", args[0])
+	usage()
+	return 2
+}
+
+// newFlagSet создает набор флагов подкоманды с описанием аргументов
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Использование: astar %s [флаги] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs разбирает флаги, допуская их после позиционных аргументов
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
+
+// pointFlag - флаг с координатами клетки в виде "x,y"
+type pointFlag struct {
+	p   Point
+	set bool
+}
+
+func (f *pointFlag) String() string {
+	if !f.set {
+		return ""
+	}
+	return fmt.Sprintf("%d,%d", f.p.x, f.p.y)
+}
+
+func (f *pointFlag) Set(s string) error {
+	x, y, ok := strings.Cut(s, ",")
+	if !ok {
+		return fmt.Errorf("want x,y")
+	}
+	var err error
+	if f.p.x, err = strconv.Atoi(strings.TrimSpace(x)); err != nil {
+		return err
+	}
+	if f.p.y, err = strconv.Atoi(strings.TrimSpace(y)); err != nil {
+		return err
+	}
+	f.set = true
+	return nil
+}
+
+// searchFlags - флаги выбора алгоритма, общие для команд с поиском
+type searchFlags struct {
+	fs        *flag.FlagSet
+	algorithm string
+	weight    float64
+	heuristic string
+	movement  string
+	queue     string
+}
+
+func registerSearchFlags(fs *flag.FlagSet) *searchFlags {
+	f := &searchFlags{fs: fs}
+	fs.StringVar(&f.algorithm, "algorithm", "astar", "алгоритм: astar, dijkstra, greedy, weighted")
+	fs.Float64Var(&f.weight, "weight", 1.5, "вес эвристики для -algorithm weighted")
+	fs.StringVar(&f.heuristic, "heuristic", "", "эвристика: manhattan, octile, euclidean, chebyshev, zero (по умолчанию - по модели движения)")
+	fs.StringVar(&f.movement, "movement", "4", "модель движения: 4 или 8")
+	fs.StringVar(&f.queue, "queue", "binary", "открытый список: binary, 4-ary, pairing, bucket, radix")
+	return f
+}
+
+// searcher создает Searcher для карты. Параметры сценария применяются
+// первыми, явно заданные флаги их переопределяют.
+func (f *searchFlags) searcher(m *MapFile) (*Searcher, error) {
+	var s *Searcher
+	if m.Scenario != nil {
+		s = m.Scenario.NewSearcher(m.Grid)
+	} else {
+		s = NewSearcher(m.Grid)
+	}
+
+	explicit := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { explicit[fl.Name] = true })
+	apply := func(name string) bool { return m.Scenario == nil || explicit[name] }
+
+	if apply("movement") {
+		movement, err := ParseMovement(f.movement)
+		if err != nil {
+			return nil, err
+		}
+		s.SetMovement(movement)
+	}
+	if f.heuristic != "" && apply("heuristic") {
+		h, err := ParseHeuristic(f.heuristic)
+		if err != nil {
+			return nil, err
+		}
+		s.SetHeuristic(h)
+	}
+	if apply("queue") {
+		kind, err := ParseQueueKind(f.queue)
+		if err != nil {
+			return nil, err
+		}
+		s.SetQueue(NewPriorityQueue(kind))
+	}
+	alg, err := ParseAlgorithm(f.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if alg == AlgorithmWeighted && f.weight < 1 {
+		return nil, fmt.Errorf("-weight must be at least 1, got %g", f.weight)
+	}
+	s.SetAlgorithm(alg, f.weight)
+	return s, nil
+}
+
+// movementOf возвращает модель движения из флагов или сценария
+func (f *searchFlags) movementOf(m *MapFile) (Movement, error) {
+	explicit := false
+	f.fs.Visit(func(fl *flag.Flag) { explicit = explicit || fl.Name == "movement" })
+	if m.Scenario != nil && !explicit {
+		if m.Scenario.Movement == 8 {
+			return Moves8, nil
+		}
+		return Moves4, nil
+	}
+	return ParseMovement(f.movement)
+}
+
+// mapFlags - флаги загрузки карты
+type mapFlags struct {
+	threshold uint
+	cellSize  int
+	start     pointFlag
+	goal      pointFlag
+}
+
+func registerMapFlags(fs *flag.FlagSet) *mapFlags {
+	f := &mapFlags{}
+	fs.UintVar(&f.threshold, "threshold", 128, "яркость пикселя, ниже которой клетка изображения - препятствие")
+	fs.IntVar(&f.cellSize, "cell-size", 1, "пикселей изображения на клетку")
+	fs.Var(&f.start, "start", "старт x,y (по умолчанию - из карты)")
+	fs.Var(&f.goal, "goal", "цель x,y (по умолчанию - из карты)")
+	return f
+}
+
+func (f *mapFlags) imageOptions() ImageGridOptions {
+	return ImageGridOptions{Threshold: uint8(min(f.threshold, 255)), CellSize: f.cellSize}
+}
+
+// load загружает карту из аргумента или встроенный сценарий, если аргумента нет,
+// и применяет -start/-goal
+func (f *mapFlags) load(fs *flag.FlagSet) (*MapFile, error) {
+	var m *MapFile
+	switch fs.NArg() {
+	case 0:
+		sc, err := ParseScenario(defaultScenario, "yaml")
+		if err != nil {
+			return nil, err
+		}
+		m = sc.MapFile()
+	case 1:
+		var err error
+		if m, err = LoadMap(fs.Arg(0), f.imageOptions()); err != nil {
+			return nil, err
+		}
+	default:
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	if f.start.set {
+		m.Start = &f.start.p
+	}
+	if f.goal.set {
+		m.Goal = &f.goal.p
+	}
+	return m, nil
+}
+
+// solveResult - результат поиска в формате JSON
+type solveResult struct {
+	Found     bool        `json:"found"`
+	Error     string      `json:"error,omitempty"`
+	Start     [2]int      `json:"start"`
+	Goal      [2]int      `json:"goal"`
+	Steps     int         `json:"steps"`
+	Cost      float64     `json:"cost"`
+	Path      [][2]int    `json:"path"`
+	Stats     SearchStats `json:"stats"`
+	ElapsedMS float64     `json:"elapsed_ms"`
+}
+
+// newSolveResult собирает результат поиска от start до goal
+func newSolveResult(start, goal Point, path []*Node, err error, stats SearchStats, elapsed time.Duration) solveResult {
+	result := solveResult{
+		Found:     err == nil,
+		Start:     [2]int{start.x, start.y},
+		Goal:      [2]int{goal.x, goal.y},
+		Path:      [][2]int{},
+		Stats:     stats,
+		ElapsedMS: float64(elapsed.Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Steps = len(path) - 1
+	result.Cost = path[len(path)-1].GCost
+	for _, node := range path {
+		result.Path = append(result.Path, [2]int{node.Position.x, node.Position.y})
+	}
+	return result
+}
+
+func cmdSolve(args []string, stdout io.Writer) error {
+	fs := newFlagSet("solve", "[карта]")
+	search := registerSearchFlags(fs)
+	maps := registerMapFlags(fs)
+	format := fs.String("format", "text", "формат вывода: text или json")
+	show := fs.Bool("show", false, "вывести карту с поиском в терминал")
+	noColor := fs.Bool("no-color", false, "выводить карту без ANSI-цветов")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("-format must be text or json, got %q", *format)
+	}
+
+	m, err := maps.load(fs)
+	if err != nil {
+		return err
+	}
+	if m.Start == nil || m.Goal == nil {
+		return fmt.Errorf("start and goal are required: the map has no markers, use -start and -goal")
+	}
+	searcher, err := search.searcher(m)
+	if err != nil {
+		return err
+	}
+
+	begin := time.Now()
+	path, searchErr := searcher.Search(*m.Start, *m.Goal)
+	result := newSolveResult(*m.Start, *m.Goal, path, searchErr, searcher.Stats(), time.Since(begin))
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		if *show {
+			view := TerminalView{
+				Path:    path,
+				Start:   m.Start,
+				Goal:    m.Goal,
+				Open:    searcher.OpenSet(),
+				Closed:  searcher.ClosedSet(),
+				NoColor: *noColor,
+			}
+			if err := RenderTerminal(stdout, m.Grid, view); err != nil {
+				return err
+			}
+		}
+		if searchErr == nil {
+			fmt.Fprintf(stdout, "Путь найден! Длина пути: %d шагов, стоимость %.2f\n", result.Steps, result.Cost)
+			fmt.Fprint(stdout, "Маршрут: ")
+			for i, p := range result.Path {
+				if i > 0 {
+					fmt.Fprint(stdout, " -> ")
+				}
+				fmt.Fprintf(stdout, "(%d,%d)", p[0], p[1])
+			}
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "Раскрыто узлов: %d, добавлено: %d, обновлено: %d, время: %.3f мс\n",
+			result.Stats.Expanded, result.Stats.Pushed, result.Stats.Updated, result.ElapsedMS)
+	}
+	return searchErr
+}
+
+func cmdRender(args []string, stdout io.Writer) error {
+	fs := newFlagSet("render", "[карта]")
+	search := registerSearchFlags(fs)
+	maps := registerMapFlags(fs)
+	output := fs.String("o", "astar.png", "файл изображения: .png, .svg, .pdf; с -pixel также .gif, .bmp")
+	detailed := fs.Bool("detailed", false, "детальное отображение PlotGridDetailed")
+	vector := fs.Bool("vector", false, "компактное векторное изображение без осей (.svg, .pdf)")
+	labels := fs.Bool("labels", false, "подписать концы и повороты пути для -vector")
+	pixel := fs.Bool("pixel", false, "попиксельное изображение без осей")
+	pixelSize := fs.Int("pixel-size", 4, "пикселей на клетку для -pixel")
+	overlay := fs.String("overlay", "none", "наложение поиска: none, sets (открытый и закрытый списки), g (стоимость от старта), order (порядок раскрытия)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	m, err := maps.load(fs)
+	if err != nil {
+		return err
+	}
+
+	opts := RenderOptions{Start: m.Start, Goal: m.Goal, LabelWaypoints: *labels}
+	if *overlay != "none" {
+		if opts.Overlay, err = ParseOverlay(*overlay); err != nil {
+			return err
+		}
+	}
+
+	var path []*Node
+	var searchErr error
+	if m.Start != nil && m.Goal != nil {
+		searcher, err := search.searcher(m)
+		if err != nil {
+			return err
+		}
+		path, searchErr = searcher.Search(*m.Start, *m.Goal)
+		if *overlay != "none" {
+			opts.Trace = searcher.Trace()
+		}
+	}
+
+	switch {
+	case *pixel:
+		err = SaveGridImage(*output, m.Grid, path, ImageGridOptions{CellSize: *pixelSize})
+	case *vector:
+		err = SaveGridVector(*output, m.Grid, path, opts)
+	case *detailed:
+		err = PlotGridDetailed(m.Grid, path, *output, opts)
+	default:
+		err = PlotGrid(m.Grid, path, *output, opts)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "График сохранен как: %s\n", *output)
+	return searchErr
+}
+
+func cmdAnimate(args []string, stdout io.Writer) error {
+	fs := newFlagSet("animate", "[карта]")
+	search := registerSearchFlags(fs)
+	maps := registerMapFlags(fs)
+	output := fs.String("o", "search.gif", "файл .gif или каталог для кадров PNG")
+	var opts AnimationOptions
+	fs.IntVar(&opts.Every, "every", 0, "кадр через каждые N раскрытий (0 - по -max-frames)")
+	fs.IntVar(&opts.MaxFrames, "max-frames", 200, "наибольшее число промежуточных кадров")
+	fs.IntVar(&opts.CellSize, "pixel-size", 0, "пикселей на клетку (0 - подобрать по размеру карты)")
+	fs.IntVar(&opts.Delay, "delay", 5, "задержка кадра в сотых долях секунды")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	m, err := maps.load(fs)
+	if err != nil {
+		return err
+	}
+	if m.Start == nil || m.Goal == nil {
+		return fmt.Errorf("start and goal are required: the map has no markers, use -start and -goal")
+	}
+	searcher, err := search.searcher(m)
+	if err != nil {
+		return err
+	}
+
+	anim, _, searchErr := RecordSearch(searcher, *m.Start, *m.Goal, opts)
+	if anim == nil {
+		return searchErr
+	}
+	if err := anim.Save(*output); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Анимация из %d кадров сохранена как: %s\n", len(anim.Frames), *output)
+	return searchErr
+}
+
+func cmdCompare(args []string, stdout io.Writer) error {
+	fs := newFlagSet("compare", "[карта]")
+	maps := registerMapFlags(fs)
+	search := &searchFlags{fs: fs}
+	fs.StringVar(&search.movement, "movement", "4", "модель движения: 4 или 8")
+	fs.StringVar(&search.queue, "queue", "binary", "открытый список: binary, 4-ary, pairing, bucket, radix")
+	solvers := fs.String("solvers", "astar,dijkstra,greedy,weighted:2", "решатели через запятую: algorithm[:weight][/heuristic]")
+	output := fs.String("o", "compare.png", "изображение с панелями: .png, .svg, .pdf; пусто - без графики")
+	table := fs.String("table", "", "файл таблицы: .md или .csv; пусто - Markdown в stdout")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	m, err := maps.load(fs)
+	if err != nil {
+		return err
+	}
+	if m.Start == nil || m.Goal == nil {
+		return fmt.Errorf("start and goal are required: the map has no markers, use -start and -goal")
+	}
+	movement, err := search.movementOf(m)
+	if err != nil {
+		return err
+	}
+	queue, err := ParseQueueKind(search.queue)
+	if err != nil {
+		return err
+	}
+
+	var configs []SolverConfig
+	for _, spec := range strings.Split(*solvers, ",") {
+		cfg, err := ParseSolverConfig(strings.TrimSpace(spec))
+		if err != nil {
+			return err
+		}
+		cfg.Movement, cfg.Queue = movement, queue
+		configs = append(configs, cfg)
+	}
+
+	results := Compare(m.Grid, *m.Start, *m.Goal, configs)
+	if *table == "" {
+		if err := WriteComparisonMarkdown(stdout, results); err != nil {
+			return err
+		}
+	} else {
+		if err := SaveComparisonTable(*table, results); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Таблица сохранена как: %s\n", *table)
+	}
+	if *output != "" {
+		if err := PlotComparison(m.Grid, *m.Start, *m.Goal, results, *output); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "График сохранен как: %s\n", *output)
+	}
+	return nil
+}
+
+func cmdView(args []string, stdout io.Writer) error {
+	fs := newFlagSet("view", "[карта]")
+	search := registerSearchFlags(fs)
+	maps := registerMapFlags(fs)
+	output := fs.String("o", "viewer.html", "файл HTML")
+	title := fs.String("title", "", "заголовок страницы (по умолчанию - размер карты)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	m, err := maps.load(fs)
+	if err != nil {
+		return err
+	}
+	movement, err := search.movementOf(m)
+	if err != nil {
+		return err
+	}
+	alg, err := ParseAlgorithm(search.algorithm)
+	if err != nil {
+		return err
+	}
+	opts := ViewerOptions{
+		Title:     *title,
+		Start:     m.Start,
+		Goal:      m.Goal,
+		Movement:  movement,
+		Algorithm: alg,
+		Weight:    search.weight,
+		Heuristic: search.heuristic,
+	}
+	if opts.Heuristic == "" && m.Scenario != nil {
+		opts.Heuristic = m.Scenario.Heuristic
+	}
+
+	// Начальный поиск показывается до первого клика по карте
+	var path []*Node
+	var searchErr error
+	if m.Start != nil && m.Goal != nil {
+		searcher, err := search.searcher(m)
+		if err != nil {
+			return err
+		}
+		path, searchErr = searcher.Search(*m.Start, *m.Goal)
+		opts.Trace = searcher.Trace()
+	}
+
+	if err := SaveViewerHTML(*output, m.Grid, path, opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Просмотрщик сохранен как: %s\n", *output)
+	return searchErr
+}
+
+func cmdServe(args []string, stdout io.Writer) error {
+	fs := newFlagSet("serve", "[карта...]")
+	addr := fs.String("addr", ":8080", "адрес для входящих соединений HTTP")
+	grpcAddr := fs.String("grpc", "", "адрес gRPC-сервиса pathrpc; пусто - без gRPC")
+	var opts ServerOptions
+	fs.Int64Var(&opts.MaxBodyBytes, "max-body", 16<<20, "наибольший размер тела запроса в байтах")
+	fs.IntVar(&opts.MaxCells, "max-cells", 16<<20, "наибольшее число клеток загружаемой сетки")
+	fs.DurationVar(&opts.Timeout, "timeout", 5*time.Second, "время поиска по умолчанию")
+	fs.DurationVar(&opts.MaxTimeout, "max-timeout", time.Minute, "наибольшее время поиска, которое может запросить клиент")
+	metrics := fs.Bool("metrics", true, "отдавать метрики OpenMetrics на /metrics")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *metrics {
+		opts.Metrics = NewSearchMetrics()
+	}
+
+	// Карты из аргументов регистрируются под именем файла без расширения
+	server := NewServer(opts)
+	for _, path := range fs.Args() {
+		m, err := LoadMap(path, ImageGridOptions{})
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		server.AddGrid(name, m)
+		fmt.Fprintf(stdout, "Сетка %s: %dx%d\n", name, m.Grid.Width, m.Grid.Height)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: *addr, Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	// По месту на каждый сервер: упавший второй не должен зависнуть на отправке
+	errc := make(chan error, 2)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Fprintf(stdout, "Сервис слушает %s\n", *addr)
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			srv.Close()
+			return err
+		}
+		gs := grpc.NewServer()
+		pathrpc.RegisterPathfinderServer(gs, NewPathfinderService(server))
+		go func() { errc <- gs.Serve(lis) }()
+		defer gs.GracefulStop()
+		fmt.Fprintf(stdout, "gRPC-сервис слушает %s\n", *grpcAddr)
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
+
+func cmdGenerate(args []string, stdout io.Writer) error {
+	fs := newFlagSet("generate", "")
+	kind := fs.String("type", "random", fmt.Sprintf("генератор: %s", strings.Join(GeneratorNames(), ", ")))
+	opts := GenerateOptions{}
+	fs.IntVar(&opts.Width, "width", 64, "ширина карты")
+	fs.IntVar(&opts.Height, "height", 64, "высота карты")
+	fs.Int64Var(&opts.Seed, "seed", 1, "зерно генератора случайных чисел")
+	fs.Float64Var(&opts.Density, "density", 0.2, "доля препятствий для -type random")
+	fs.IntVar(&opts.CorridorWidth, "corridor", 1, "ширина коридоров лабиринта")
+	fs.Float64Var(&opts.Braid, "braid", 0, "доля тупиков лабиринта, превращаемых в петли (0-1)")
+	fs.IntVar(&opts.Iterations, "iterations", 5, "шаги клеточного автомата для -type cave")
+	fs.IntVar(&opts.RoomSize, "room-size", 5, "минимальная сторона комнаты для -type dungeon")
+	fs.Float64Var(&opts.Scale, "scale", 16, "размер форм рельефа в клетках для -type terrain")
+	fs.Float64Var(&opts.MaxCost, "max-cost", 5, "стоимость вершин рельефа для -type terrain")
+	var start, goal pointFlag
+	fs.Var(&start, "start", "старт x,y (по умолчанию - выбор генератора)")
+	fs.Var(&goal, "goal", "цель x,y (по умолчанию - выбор генератора)")
+	fs.BoolVar(&opts.Connect, "connect", false, "прорубить проход между стартом и целью, если они не связаны")
+	output := fs.String("o", "", "файл карты: .txt, .map, .grid, .png, .gif, .bmp (по умолчанию - текст в stdout)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	if start.set {
+		opts.Start = &start.p
+	}
+	if goal.set {
+		opts.Goal = &goal.p
+	}
+
+	m, err := Generate(*kind, opts)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return WriteASCII(stdout, m.Grid, m.Start, m.Goal)
+	}
+	if err := SaveMap(*output, m, ImageGridOptions{}); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Карта %dx%d сохранена как: %s\n", m.Grid.Width, m.Grid.Height, *output)
+	return nil
+}
+
+func cmdBench(args []string, stdout io.Writer) error {
+	fs := newFlagSet("bench", "[карта ...]")
+	suite := fs.String("suite", "all", "набор: all - алгоритмы и очереди, search - алгоритмы с двоичной кучей, queues - очереди с A*")
+	movement := fs.String("movement", "4", "модель движения: 4 или 8")
+	weight := fs.Float64("weight", 1.5, "вес эвристики для weighted")
+	size := fs.Int("size", 256, "сторона сгенерированных карт, если карты не заданы")
+	seed := fs.Int64("seed", 1, "зерно сгенерированных карт")
+	minTime := fs.Duration("time", 200*time.Millisecond, "время замера одной комбинации")
+	scen := fs.String("scen", "", "вместо набора решить сценарии MovingAI (.scen)")
+	mapFile := fs.String("map", "", "карта MovingAI (.map) для -scen, по умолчанию из сценария")
+	maps := registerMapFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if *scen != "" {
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return errUsage
+		}
+		return RunMovingAIFile(stdout, *scen, *mapFile)
+	}
+
+	opts := BenchOptions{Weight: *weight, MinTime: *minTime}
+	switch *suite {
+	case "all":
+	case "search":
+		opts.Queues = []QueueKind{QueueBinary}
+	case "queues":
+		opts.Algorithms = []Algorithm{AlgorithmAStar}
+	default:
+		return fmt.Errorf("unknown suite %q (want all, search or queues)", *suite)
+	}
+	var err error
+	if opts.Movement, err = ParseMovement(*movement); err != nil {
+		return err
+	}
+
+	var benchMaps []BenchMap
+	if fs.NArg() == 0 {
+		if benchMaps, err = GeneratedBenchMaps(*size, *seed); err != nil {
+			return err
+		}
+	}
+	for _, path := range fs.Args() {
+		m, err := LoadMap(path, maps.imageOptions())
+		if err != nil {
+			return err
+		}
+		if maps.start.set {
+			m.Start = &maps.start.p
+		}
+		if maps.goal.set {
+			m.Goal = &maps.goal.p
+		}
+		if m.Start == nil || m.Goal == nil {
+			return fmt.Errorf("%s: map has no start and goal, set -start and -goal", path)
+		}
+		benchMaps = append(benchMaps, BenchMap{Name: filepath.Base(path), Grid: m.Grid, Start: *m.Start, Goal: *m.Goal})
+	}
+	RunSearchBenchmarks(stdout, benchMaps, opts)
+	return nil
+}
+
+// pointPattern находит координаты "x,y" в текстовом файле пути
+var pointPattern = regexp.MustCompile(`(-?\d+)\s*,\s*(-?\d+)`)
+
+// readPathFile читает путь из JSON-вывода solve или из текста с парами x,y
+func readPathFile(filename string) ([]Point, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []Point
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		var result solveResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for _, p := range result.Path {
+			points = append(points, Point{p[0], p[1]})
+		}
+		return points, nil
+	}
+
+	for _, match := range pointPattern.FindAllStringSubmatch(string(data), -1) {
+		x, _ := strconv.Atoi(match[1])
+		y, _ := strconv.Atoi(match[2])
+		points = append(points, Point{x, y})
+	}
+	return points, nil
+}
+
+func cmdValidate(args []string, stdout io.Writer) error {
+	fs := newFlagSet("validate", "карта [путь.json|путь.txt]")
+	search := registerSearchFlags(fs)
+	maps := registerMapFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	m, err := LoadMap(fs.Arg(0), maps.imageOptions())
+	if err != nil {
+		return err
+	}
+	grid := m.Grid
+	obstacles := 0
+	for _, blocked := range grid.Obstacles {
+		if blocked {
+			obstacles++
+		}
+	}
+	components := 0
+	if m.Components != nil {
+		for _, label := range m.Components {
+			components = max(components, int(label)+1)
+		}
+	} else {
+		_, components = Components(grid)
+	}
+	fmt.Fprintf(stdout, "Карта %dx%d: препятствий %d, свободных клеток %d, областей связности %d\n",
+		grid.Width, grid.Height, obstacles, grid.Width*grid.Height-obstacles, components)
+
+	var problems []error
+	for _, end := range []struct {
+		name string
+		p    *Point
+	}{{"start", m.Start}, {"goal", m.Goal}} {
+		if end.p != nil && !grid.IsValid(*end.p) {
+			problems = append(problems, fmt.Errorf("%s (%d,%d) isn't available", end.name, end.p.x, end.p.y))
+		}
+	}
+
+	if fs.NArg() == 2 {
+		path, err := readPathFile(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		movement, err := search.movementOf(m)
+		if err != nil {
+			return err
+		}
+
+		cost, err := grid.PathCost(path, movement)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("path: %w", err))
+		} else {
+			fmt.Fprintf(stdout, "Путь: %d шагов, стоимость %.4f\n", len(path)-1, cost)
+
+			first, last := path[0], path[len(path)-1]
+			if m.Start != nil && first != *m.Start {
+				problems = append(problems, fmt.Errorf("path starts at (%d,%d), map start is (%d,%d)", first.x, first.y, m.Start.x, m.Start.y))
+			}
+			if m.Goal != nil && last != *m.Goal {
+				problems = append(problems, fmt.Errorf("path ends at (%d,%d), map goal is (%d,%d)", last.x, last.y, m.Goal.x, m.Goal.y))
+			}
+
+			// Сравниваем с оптимальным путем между теми же концами
+			searcher, err := search.searcher(m)
+			if err != nil {
+				return err
+			}
+			searcher.SetAlgorithm(AlgorithmAStar, 1)
+			if optimal, err := searcher.Search(first, last); err == nil {
+				best := optimal[len(optimal)-1].GCost
+				if best > 0 {
+					fmt.Fprintf(stdout, "Оптимальная стоимость: %.4f (отношение %.4f)\n", best, cost/best)
+				} else {
+					// Старт совпадает с целью - отношение не определено
+					fmt.Fprintf(stdout, "Оптимальная стоимость: %.4f\n", best)
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Join(problems...)
+	}
+	fmt.Fprintln(stdout, "OK")
+	return nil
+}
+
+func cmdDiff(args []string, stdout io.Writer) error {
+	fs := newFlagSet("diff", "старая-карта новая-карта")
+	maps := registerMapFlags(fs)
+	limit := fs.Int("limit", 20, "сколько клеток каждого вида перечислить; 0 - только итоги")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var grids [2]*Grid
+	for i := range grids {
+		m, err := LoadMap(fs.Arg(i), maps.imageOptions())
+		if err != nil {
+			return err
+		}
+		grids[i] = m.Grid
+	}
+	d, err := Diff(grids[0], grids[1])
+	if err != nil {
+		return err
+	}
+
+	list := func(title string, n int, item func(i int) string) {
+		fmt.Fprintf(stdout, "%s: %d\n", title, n)
+		for i := 0; i < min(n, *limit); i++ {
+			fmt.Fprintf(stdout, "  %s\n", item(i))
+		}
+		if n > *limit && *limit > 0 {
+			fmt.Fprintf(stdout, "  ... еще %d\n", n-*limit)
+		}
+	}
+	added, removed, costs := d.Added(), d.Removed(), d.CostChanges()
+	list("Добавлено препятствий", len(added), func(i int) string {
+		return fmt.Sprintf("(%d,%d)", added[i].x, added[i].y)
+	})
+	list("Убрано препятствий", len(removed), func(i int) string {
+		return fmt.Sprintf("(%d,%d)", removed[i].x, removed[i].y)
+	})
+	list("Изменено стоимостей", len(costs), func(i int) string {
+		c := costs[i]
+		return fmt.Sprintf("(%d,%d) %g -> %g", c.Point.x, c.Point.y, c.OldCost, c.Cost)
+	})
+	return nil
+}
+
+func cmdRun(args []string, stdout io.Writer) error {
+	fs := newFlagSet("run", "[сценарий.json|сценарий.yaml]")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	var sc *Scenario
+	var err error
+	switch fs.NArg() {
+	case 0:
+		sc, err = ParseScenario(defaultScenario, "yaml")
+	case 1:
+		sc, err = LoadScenario(fs.Arg(0))
+	default:
+		fs.Usage()
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "Поиск пути с помощью алгоритма A*")
+	fmt.Fprintln(stdout, "==================================")
+	return sc.Run(stdout)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// SolverConfig - настройка решателя для сравнения
+type SolverConfig struct {
+	Name      string
+	Algorithm Algorithm
+	Weight    float64   // вес эвристики для AlgorithmWeighted
+	Heuristic Heuristic // nil - эвристика по умолчанию для модели движения
+	Movement  Movement
+	Queue     QueueKind
+}
+
+// ParseSolverConfig разбирает описание решателя вида
+// algorithm[:weight][/heuristic], например "astar/octile" или "weighted:2".
+// Описание становится именем решателя.
+func ParseSolverConfig(spec string) (SolverConfig, error) {
+	cfg := SolverConfig{Name: spec, Weight: 1}
+	rest := spec
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		h, err := ParseHeuristic(rest[i+1:])
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Heuristic, rest = h, rest[:i]
+	}
+	if i := strings.IndexByte(rest, ':'); i >= 0 {
+		w, err := strconv.ParseFloat(rest[i+1:], 64)
+		if err != nil || w < 1 {
+			return cfg, fmt.Errorf("solver %q: weight must be a number of at least 1", spec)
+		}
+		cfg.Weight, rest = w, rest[:i]
+	}
+	alg, err := ParseAlgorithm(rest)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Algorithm = alg
+	return cfg, nil
+}
+
+// NewSearcher создает Searcher с настройками решателя
+func (c SolverConfig) NewSearcher(grid *Grid) *Searcher {
+	s := NewSearcher(grid)
+	s.SetMovement(c.Movement)
+	s.SetHeuristic(c.Heuristic)
+	s.SetAlgorithm(c.Algorithm, c.Weight)
+	s.SetQueue(NewPriorityQueue(c.Queue))
+	return s
+}
+
+// ComparisonResult - результат одного решателя
+type ComparisonResult struct {
+	Config  SolverConfig
+	Path    []*Node
+	Err     error
+	Stats   SearchStats
+	Elapsed time.Duration
+	Trace   *SearchTrace
+}
+
+// Cost возвращает стоимость найденного пути, +Inf - путь не найден
+func (r ComparisonResult) Cost() float64 {
+	if len(r.Path) == 0 {
+		return math.Inf(1)
+	}
+	return r.Path[len(r.Path)-1].GCost
+}
+
+// Compare решает одну задачу всеми решателями по очереди
+func Compare(grid *Grid, start, goal Point, configs []SolverConfig) []ComparisonResult {
+	results := make([]ComparisonResult, len(configs))
+	for i, cfg := range configs {
+		searcher := cfg.NewSearcher(grid)
+		begin := time.Now()
+		path, err := searcher.Search(start, goal)
+		results[i] = ComparisonResult{
+			Config:  cfg,
+			Path:    path,
+			Err:     err,
+			Stats:   searcher.Stats(),
+			Elapsed: time.Since(begin),
+			Trace:   searcher.Trace(),
+		}
+	}
+	return results
+}
+
+// comparisonRows формирует таблицу результатов. Excess - превышение
+// стоимости над лучшим найденным путем в процентах.
+func comparisonRows(results []ComparisonResult) [][]string {
+	best := math.Inf(1)
+	for _, r := range results {
+		best = math.Min(best, r.Cost())
+	}
+
+	rows := [][]string{{"Solver", "Found", "Steps", "Cost", "Excess %", "Expanded", "Pushed", "Updated", "Time ms"}}
+	for _, r := range results {
+		row := []string{r.Config.Name, "no", "-", "-", "-"}
+		if r.Err == nil {
+			row = []string{
+				r.Config.Name,
+				"yes",
+				strconv.Itoa(len(r.Path) - 1),
+				strconv.FormatFloat(r.Cost(), 'f', 2, 64),
+				strconv.FormatFloat((r.Cost()/best-1)*100, 'f', 1, 64),
+			}
+		}
+		rows = append(rows, append(row,
+			strconv.Itoa(r.Stats.Expanded),
+			strconv.Itoa(r.Stats.Pushed),
+			strconv.Itoa(r.Stats.Updated),
+			strconv.FormatFloat(float64(r.Elapsed.Microseconds())/1000, 'f', 3, 64),
+		))
+	}
+	return rows
+}
+
+// WriteComparisonMarkdown выводит таблицу результатов в Markdown
+func WriteComparisonMarkdown(w io.Writer, results []ComparisonResult) error {
+	rows := comparisonRows(results)
+	for i, row := range rows {
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | ")); err != nil {
+			return err
+		}
+		if i == 0 {
+			sep := make([]string, len(row))
+			for j := range sep {
+				sep[j] = "---"
+				if j > 0 {
+					sep[j] = "---:" // числа выравниваются вправо
+				}
+			}
+			if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(sep, " | ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteComparisonCSV выводит таблицу результатов в CSV
+func WriteComparisonCSV(w io.Writer, results []ComparisonResult) error {
+	cw := csv.NewWriter(w)
+	cw.WriteAll(comparisonRows(results))
+	return cw.Error()
+}
+
+// SaveComparisonTable сохраняет таблицу: .csv - CSV, иначе Markdown
+func SaveComparisonTable(filename string, results []ComparisonResult) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		err = WriteComparisonCSV(f, results)
+	} else {
+		err = WriteComparisonMarkdown(f, results)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// PlotComparison рисует по панели PlotGrid на решатель - раскрытые
+// клетки и путь - и сохраняет их одним изображением; формат по расширению
+func PlotComparison(grid *Grid, start, goal Point, results []ComparisonResult, filename string) error {
+	if len(results) == 0 {
+		return fmt.Errorf("nothing to plot: no solver results")
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(len(results)))))
+	rows := (len(results) + cols - 1) / cols
+	panel := 5 * vg.Inch
+
+	plots := make([][]*plot.Plot, rows)
+	for i := range plots {
+		plots[i] = make([]*plot.Plot, cols)
+	}
+	for i, r := range results {
+		title := fmt.Sprintf("%s: no path", r.Config.Name)
+		if r.Err == nil {
+			title = fmt.Sprintf("%s: cost %.2f, expanded %d", r.Config.Name, r.Cost(), r.Stats.Expanded)
+		}
+		opts := RenderOptions{Title: title, Start: &start, Goal: &goal, Trace: r.Trace}
+		p, err := gridPlot(grid, r.Path, opts.withDefaults("", panel, r.Path))
+		if err != nil {
+			return err
+		}
+		plots[i/cols][i%cols] = p
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	canvas, err := draw.NewFormattedCanvas(panel*vg.Length(cols), panel*vg.Length(rows), format)
+	if err != nil {
+		return err
+	}
+	tiles := draw.Tiles{
+		Rows: rows, Cols: cols,
+		PadX: 4 * vg.Millimeter, PadY: 4 * vg.Millimeter,
+		PadTop: 2 * vg.Millimeter, PadBottom: 2 * vg.Millimeter,
+		PadLeft: 2 * vg.Millimeter, PadRight: 2 * vg.Millimeter,
+	}
+	canvases := plot.Align(plots, tiles, draw.New(canvas))
+	for j := range plots {
+		for i, p := range plots[j] {
+			if p != nil {
+				p.Draw(canvases[j][i])
+			}
+		}
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	_, err = canvas.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+// Components размечает 4-связные области свободных клеток.
+// Возвращает метку для каждой клетки (-1 у препятствий) и число областей.
+func Components(grid *Grid) ([]int32, int) {
+	labels := make([]int32, grid.Width*grid.Height)
+	for i := range labels {
+		labels[i] = -1
+	}
+
+	count := 0
+	var queue []int
+	for i := range labels {
+		if grid.Obstacles[i] || labels[i] >= 0 {
+			continue
+		}
+		label := int32(count)
+		count++
+		labels[i] = label
+		queue = append(queue[:0], i)
+		for len(queue) > 0 {
+			cell := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			p := Point{cell % grid.Width, cell / grid.Width}
+			for _, dir := range directions4 {
+				next := Point{p.x + dir[0], p.y + dir[1]}
+				if !grid.IsValid(next) {
+					continue
+				}
+				if j := grid.index(next); labels[j] < 0 {
+					labels[j] = label
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+	return labels, count
+}
+
+// FreeCellsConnected сообщает, достижима ли любая свободная клетка из любой другой
+func FreeCellsConnected(grid *Grid) bool {
+	_, count := Components(grid)
+	return count <= 1
+}
+
+// EnsureConnected гарантирует проходимость между start и goal: освобождает
+// обе клетки и, если пути нет, прорубает проход через наименьшее число
+// препятствий (поиск в ширину 0-1). Возвращает число освобожденных клеток.
+// Все клетки освобождаются одной правкой сетки (Update).
+func EnsureConnected(grid *Grid, start, goal Point) int {
+	carved := 0
+	grid.Update(func() error {
+		carved = carve(grid, start, goal)
+		return nil
+	})
+	return carved
+}
+
+func carve(grid *Grid, start, goal Point) int {
+	carved := 0
+	for _, p := range []Point{start, goal} {
+		if grid.IsObstacle(p) {
+			grid.RemoveObstacle(p)
+			carved++
+		}
+	}
+
+	size := grid.Width * grid.Height
+	dist := make([]int32, size)
+	parent := make([]int32, size)
+	for i := range dist {
+		dist[i] = -1
+		parent[i] = -1
+	}
+
+	// Два уровня: front - клетки текущего числа пробитых стен, back - на одну больше
+	s, g := grid.index(start), grid.index(goal)
+	front, back := []int{s}, []int{}
+	dist[s] = 0
+	for len(front) > 0 || len(back) > 0 {
+		if len(front) == 0 {
+			front, back = back, front[:0]
+		}
+		cell := front[len(front)-1]
+		front = front[:len(front)-1]
+		if cell == g {
+			break
+		}
+
+		p := Point{cell % grid.Width, cell / grid.Width}
+		for _, dir := range directions4 {
+			next := Point{p.x + dir[0], p.y + dir[1]}
+			if !grid.InBounds(next) {
+				continue
+			}
+			j := grid.index(next)
+			w := int32(0)
+			if grid.Obstacles[j] {
+				w = 1
+			}
+			if dist[j] >= 0 && dist[j] <= dist[cell]+w {
+				continue
+			}
+			dist[j] = dist[cell] + w
+			parent[j] = int32(cell)
+			if w == 0 {
+				front = append(front, j)
+			} else {
+				back = append(back, j)
+			}
+		}
+	}
+
+	for cell := g; cell != s && cell >= 0; cell = int(parent[cell]) {
+		if grid.Obstacles[cell] {
+			grid.RemoveObstacle(Point{cell % grid.Width, cell / grid.Width})
+			carved++
+		}
+	}
+	return carved
+}
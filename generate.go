@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// GenerateOptions - общие параметры процедурных генераторов карт
+type GenerateOptions struct {
+	Width, Height int
+	Seed          int64
+	Density       float64 // доля препятствий для "random"
+	CorridorWidth int     // ширина коридоров лабиринта в клетках, по умолчанию 1
+	Braid         float64 // доля тупиков лабиринта, превращаемых в петли, [0, 1]
+	Iterations    int     // шаги клеточного автомата пещеры, по умолчанию 5
+	RoomSize      int     // минимальная сторона комнаты подземелья, по умолчанию 5
+	Scale         float64 // размер форм рельефа в клетках, по умолчанию 16
+	MaxCost       float64 // стоимость вершин рельефа, по умолчанию 5
+
+	Start, Goal *Point // концы; nil - выбор генератора
+	Connect     bool   // прорубить проход, если концы не связаны
+}
+
+// Generator строит карту по параметрам; одинаковый Seed дает одинаковую карту
+type Generator func(opts GenerateOptions) (*MapFile, error)
+
+// generators - генераторы, доступные по имени в CLI
+var generators = map[string]Generator{
+	"random":           GenerateRandom,
+	"maze-backtracker": GenerateMazeBacktracker,
+	"maze-prim":        GenerateMazePrim,
+	"maze-kruskal":     GenerateMazeKruskal,
+	"maze-wilson":      GenerateMazeWilson,
+	"maze-eller":       GenerateMazeEller,
+	"cave":             GenerateCave,
+	"dungeon":          GenerateDungeon,
+	"terrain":          GenerateTerrain,
+}
+
+// GeneratorNames возвращает отсортированные имена генераторов
+func GeneratorNames() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Generate строит карту генератором с заданным именем
+func Generate(name string, opts GenerateOptions) (*MapFile, error) {
+	gen, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q (want one of %v)", name, GeneratorNames())
+	}
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, fmt.Errorf("map size must be positive, got %dx%d", opts.Width, opts.Height)
+	}
+	return gen(opts)
+}
+
+// GenerateRandom расставляет препятствия независимо с вероятностью Density,
+// оставляя свободными старт и цель (по умолчанию - противоположные углы)
+func GenerateRandom(opts GenerateOptions) (*MapFile, error) {
+	if opts.Density < 0 || opts.Density > 1 {
+		return nil, fmt.Errorf("density must be within [0, 1], got %g", opts.Density)
+	}
+
+	grid := NewGrid(opts.Width, opts.Height)
+	start, goal := opts.endpoints(Point{0, 0}, Point{opts.Width - 1, opts.Height - 1})
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			p := Point{x, y}
+			if rng.Float64() < opts.Density && p != start && p != goal {
+				grid.AddObstacle(p)
+			}
+		}
+	}
+	return opts.finish(grid, start, goal)
+}
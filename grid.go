@@ -1,56 +1,153 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type Grid struct {
 	Width, Height int
-	Obstacles map[string]bool
+	Obstacles     []bool    // препятствия построчно: индекс y*Width + x
+	Costs         []float64 // стоимость входа в клетку (рельеф); nil - все клетки стоят 1
+	version       uint64    // число правок через методы сетки
+	edits         gridEdit  // текущая правка, см. gridedit.go
+	shared        bool      // массивы общие со снимком, правка сначала копирует их
+	listeners     []*gridListener
+}
+
+// Version возвращает счетчик правок. Он растет на единицу за каждую
+// правку, изменившую клетки: вызов AddObstacle, RemoveObstacle, SetCost,
+// FillRect, DrawLine, DrawCircle или целую транзакцию Update. Прямые
+// записи в Obstacles и Costs его не меняют.
+func (g *Grid) Version() uint64 {
+	return g.version
 }
 
 func NewGrid(width, height int) *Grid {
 	return &Grid{
-		Width: width,
-		Height: height,
-		Obstacles: make(map[string]bool),
+		Width:     width,
+		Height:    height,
+		Obstacles: make([]bool, width*height),
 	}
 }
 
+// InBounds сообщает, лежит ли точка внутри сетки
+func (g *Grid) InBounds(point Point) bool {
+	return point.x >= 0 && point.x < g.Width && point.y >= 0 && point.y < g.Height
+}
+
+// index возвращает индекс клетки в плоских массивах сетки
+func (g *Grid) index(point Point) int {
+	return point.y*g.Width + point.x
+}
+
+// AddObstacle помечает клетку препятствием, точки вне сетки игнорируются
 func (g *Grid) AddObstacle(point Point) {
-	key := fmt.Sprintf("%d,%d", point.x, point.y)
-	g.Obstacles[key] = true
+	g.setObstacle(point, true)
 }
 
-func (g *Grid) IsObstacle(point Point) bool {
-	key := fmt.Sprintf("%d,%d", point.x, point.y)
-	return g.Obstacles[key]
+// RemoveObstacle освобождает клетку, точки вне сетки игнорируются
+func (g *Grid) RemoveObstacle(point Point) {
+	g.setObstacle(point, false)
 }
 
-func (g *Grid) IsValid(point Point) bool {
-	return point.x >= 0 && point.x < g.Width && point.y >= 0 && point.y < g.Height && !g.IsObstacle(point)
-}
-
-// Получение соседей для текущей вершины
-func (g *Grid) GetNeighbors(node *Node) []*Node {
-	neighbors := make([]*Node, 0)
-
-	directions := [][2]int{
-        {0, 1},  // вверх
-        {0, -1}, // вниз
-        {1, 0},  // вправо
-        {-1, 0}, // влево
-    }
-
-	for _, dir := range directions {
-		newX := node.Position.x + dir[0]
-		newY := node.Position.y + dir[1]
-		
-		if g.IsValid(Point{newX, newY}) {
-			neighbor := &Node{
-				Position: Point{newX, newY},
-			}
-			neighbors = append(neighbors, neighbor)
+func (g *Grid) setObstacle(point Point, obstacle bool) {
+	if !g.InBounds(point) || g.Obstacles[g.index(point)] == obstacle {
+		return
+	}
+	g.unshare()
+	g.begin()
+	g.record(point)
+	g.Obstacles[g.index(point)] = obstacle
+	g.commit()
+}
+
+// SetCost задает стоимость входа в клетку. Стоимость меньше 1 сделала бы
+// эвристики недопустимыми, поэтому она, как и бесконечная стоимость, NaN
+// и клетки вне сетки, игнорируется.
+func (g *Grid) SetCost(point Point, cost float64) {
+	if !g.InBounds(point) || !validCost(cost) {
+		return
+	}
+	if g.Cost(point) == cost {
+		return
+	}
+	g.unshare()
+	if g.Costs == nil {
+		g.Costs = make([]float64, g.Width*g.Height)
+		for i := range g.Costs {
+			g.Costs[i] = 1
+		}
+	}
+	g.begin()
+	g.record(point)
+	g.Costs[g.index(point)] = cost
+	g.commit()
+}
+
+// validCost сообщает, допустима ли стоимость клетки: конечное число
+// не меньше 1
+func validCost(cost float64) bool {
+	return cost >= 1 && !math.IsInf(cost, 1)
+}
+
+// Cost возвращает стоимость входа в клетку
+func (g *Grid) Cost(point Point) float64 {
+	if g.Costs == nil || !g.InBounds(point) {
+		return 1
+	}
+	return g.Costs[g.index(point)]
+}
+
+// StepCost возвращает стоимость шага в соседнюю клетку и false, если шаг
+// недопустим: клетка занята или диагональ срезает угол препятствия
+func (g *Grid) StepCost(from, to Point) (float64, bool) {
+	if !g.IsValid(to) {
+		return 0, false
+	}
+	dx, dy := to.x-from.x, to.y-from.y
+	if dx != 0 && dy != 0 {
+		if !g.IsValid(Point{from.x + dx, from.y}) || !g.IsValid(Point{from.x, from.y + dy}) {
+			return 0, false
 		}
+		return math.Sqrt2 * g.Cost(to), true
+	}
+	return g.Cost(to), true
+}
+
+// PathCost проверяет, что путь состоит из допустимых шагов модели движения,
+// и возвращает его стоимость
+func (g *Grid) PathCost(path []Point, movement Movement) (float64, error) {
+	if len(path) == 0 {
+		return 0, fmt.Errorf("path is empty")
+	}
+	if !g.IsValid(path[0]) {
+		return 0, fmt.Errorf("step 0: cell (%d,%d) isn't available", path[0].x, path[0].y)
 	}
 
-	return neighbors
-}
\ No newline at end of file
+	cost := 0.0
+	for i := 1; i < len(path); i++ {
+		from, to := path[i-1], path[i]
+		dx, dy := to.x-from.x, to.y-from.y
+		if dx < -1 || dx > 1 || dy < -1 || dy > 1 || (dx == 0 && dy == 0) {
+			return 0, fmt.Errorf("step %d: (%d,%d) -> (%d,%d) is not a move to a neighbor", i, from.x, from.y, to.x, to.y)
+		}
+		if dx != 0 && dy != 0 && movement != Moves8 {
+			return 0, fmt.Errorf("step %d: diagonal move (%d,%d) -> (%d,%d) with %s-connected movement", i, from.x, from.y, to.x, to.y, movement)
+		}
+		step, ok := g.StepCost(from, to)
+		if !ok {
+			return 0, fmt.Errorf("step %d: (%d,%d) -> (%d,%d) is blocked", i, from.x, from.y, to.x, to.y)
+		}
+		cost += step
+	}
+	return cost, nil
+}
+
+func (g *Grid) IsObstacle(point Point) bool {
+	return g.InBounds(point) && g.Obstacles[g.index(point)]
+}
+
+func (g *Grid) IsValid(point Point) bool {
+	return g.InBounds(point) && !g.Obstacles[g.index(point)]
+}
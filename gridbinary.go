@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+// Двоичный формат карты (.grid). Все числа - uvarint, кроме float64
+// (little-endian) и контрольной суммы.
+//
+//	"AGRD" версия:byte ширина высота
+//	секции: тег:byte длина полезная-нагрузка
+//	CRC32 (IEEE, little-endian) всех предыдущих байт
+//
+// Секции:
+//
+//	'O' препятствия: длины чередующихся серий свободных клеток и
+//	    препятствий построчно, первая серия - свободные (может быть 0)
+//	'C' стоимости: число уровней, уровни float64, затем серии
+//	    (индекс уровня, длина). Различных стоимостей больше
+//	    maxCostLevels - уровни квантуются равномерно от min до max.
+//	'P' концы: флаги (1 - старт, 2 - цель) и координаты x, y
+//	'L' области связности (Components): серии (метка+1, длина)
+//
+// Неизвестные секции пропускаются, поэтому новые данные можно добавлять
+// без смены версии. Без секции 'C' все клетки стоят 1.
+const (
+	binaryMagic   = "AGRD"
+	binaryVersion = 1
+	maxCostLevels = 1 << 16
+	// maxBinaryCells - тот же предел размера сетки, что и у остальных
+	// форматов карт (maxMapCells): карту, прочитанную из .map или .txt,
+	// можно сохранить в .grid и прочитать обратно
+	maxBinaryCells = maxMapCells
+)
+
+const (
+	sectionObstacles  = 'O'
+	sectionCosts      = 'C'
+	sectionEndpoints  = 'P'
+	sectionComponents = 'L'
+)
+
+// ErrCorruptGrid - двоичные данные карты повреждены или не в формате .grid
+var ErrCorruptGrid = errors.New("corrupt binary grid")
+
+// BinaryOptions - что записывать в двоичную карту кроме сетки
+type BinaryOptions struct {
+	// Components - записать метки областей связности, чтобы не считать
+	// их при загрузке
+	Components bool
+}
+
+// MarshalBinary кодирует сетку в двоичный формат .grid
+func (g *Grid) MarshalBinary() ([]byte, error) {
+	return EncodeBinaryMap(&MapFile{Grid: g}, BinaryOptions{})
+}
+
+// UnmarshalBinary заменяет сетку декодированной из формата .grid. Если
+// на сетку подписаны, новая сетка должна быть того же размера: она
+// применяется одной транзакцией Update, и подписчики получают изменившиеся
+// клетки. Без подписчиков размер может быть любым, версия растет.
+func (g *Grid) UnmarshalBinary(data []byte) error {
+	m, err := DecodeBinaryMap(data)
+	if err != nil {
+		return err
+	}
+	src := m.Grid
+	if len(g.listeners) == 0 {
+		g.Width, g.Height = src.Width, src.Height
+		g.Obstacles, g.Costs = src.Obstacles, src.Costs
+		g.shared = false
+		g.version++
+		return nil
+	}
+	if src.Width != g.Width || src.Height != g.Height {
+		return fmt.Errorf("binary grid: %dx%d grid has subscribers and cannot become %dx%d", g.Width, g.Height, src.Width, src.Height)
+	}
+	return g.Update(func() error {
+		for y := 0; y < g.Height; y++ {
+			for x := 0; x < g.Width; x++ {
+				p := Point{x, y}
+				g.setObstacle(p, src.IsObstacle(p))
+				g.SetCost(p, src.Cost(p))
+			}
+		}
+		return nil
+	})
+}
+
+// EncodeBinaryMap кодирует карту с концами и дополнительными данными
+func EncodeBinaryMap(m *MapFile, opts BinaryOptions) ([]byte, error) {
+	g := m.Grid
+	if g.Width <= 0 || g.Height <= 0 || g.Width*g.Height > maxBinaryCells {
+		return nil, fmt.Errorf("binary grid: unsupported size %dx%d", g.Width, g.Height)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	buf.WriteByte(binaryVersion)
+	buf.Write(binary.AppendUvarint(nil, uint64(g.Width)))
+	buf.Write(binary.AppendUvarint(nil, uint64(g.Height)))
+
+	section := func(tag byte, payload []byte) {
+		buf.WriteByte(tag)
+		buf.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+		buf.Write(payload)
+	}
+	section(sectionObstacles, encodeObstacles(g.Obstacles))
+	if g.Costs != nil {
+		for i, c := range g.Costs {
+			if !validCost(c) {
+				return nil, fmt.Errorf("binary grid: cell %d has cost %g", i, c)
+			}
+		}
+		section(sectionCosts, encodeCosts(g.Costs))
+	}
+	if m.Start != nil || m.Goal != nil {
+		section(sectionEndpoints, encodeEndpoints(m.Start, m.Goal))
+	}
+	if opts.Components {
+		labels := m.Components
+		if labels == nil {
+			labels, _ = Components(g)
+		}
+		section(sectionComponents, encodeRuns(len(labels), func(i int) uint64 { return uint64(labels[i] + 1) }))
+	}
+	return binary.LittleEndian.AppendUint32(buf.Bytes(), crc32.ChecksumIEEE(buf.Bytes())), nil
+}
+
+// encodeRuns записывает серии одинаковых значений value(i) парами
+// (значение, длина)
+func encodeRuns(n int, value func(i int) uint64) []byte {
+	var out []byte
+	for i := 0; i < n; {
+		v, j := value(i), i+1
+		for j < n && value(j) == v {
+			j++
+		}
+		out = binary.AppendUvarint(out, v)
+		out = binary.AppendUvarint(out, uint64(j-i))
+		i = j
+	}
+	return out
+}
+
+func encodeObstacles(obstacles []bool) []byte {
+	var out []byte
+	current := false
+	for i := 0; i < len(obstacles); {
+		j := i
+		for j < len(obstacles) && obstacles[j] == current {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i))
+		current = !current
+		i = j
+	}
+	return out
+}
+
+func encodeCosts(costs []float64) []byte {
+	levels := slices.Clone(costs)
+	slices.Sort(levels)
+	levels = slices.Compact(levels)
+	level := func(i int) uint64 {
+		n, _ := slices.BinarySearch(levels, costs[i])
+		return uint64(n)
+	}
+	if len(levels) > maxCostLevels {
+		lo, hi := levels[0], levels[len(levels)-1]
+		step := (hi - lo) / (maxCostLevels - 1)
+		levels = make([]float64, maxCostLevels)
+		for i := range levels {
+			levels[i] = lo + float64(i)*step
+		}
+		levels[len(levels)-1] = hi
+		level = func(i int) uint64 {
+			return uint64(math.Round((costs[i] - lo) / step))
+		}
+	}
+
+	out := binary.AppendUvarint(nil, uint64(len(levels)))
+	for _, v := range levels {
+		out = binary.LittleEndian.AppendUint64(out, math.Float64bits(v))
+	}
+	return append(out, encodeRuns(len(costs), level)...)
+}
+
+func encodeEndpoints(start, goal *Point) []byte {
+	var flags byte
+	var coords []byte
+	for i, p := range []*Point{start, goal} {
+		if p != nil {
+			flags |= 1 << i
+			coords = binary.AppendUvarint(coords, uint64(p.x))
+			coords = binary.AppendUvarint(coords, uint64(p.y))
+		}
+	}
+	return append([]byte{flags}, coords...)
+}
+
+// binaryReader читает uvarint и байты, запоминая первую ошибку
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: "+format, append([]any{ErrCorruptGrid}, args...)...)
+	}
+}
+
+func (r *binaryReader) uvarint(limit uint64, what string) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail("truncated %s", what)
+		return 0
+	}
+	if v > limit {
+		r.fail("%s %d exceeds %d", what, v, limit)
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) bytes(n int, what string) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.fail("truncated %s", what)
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// runs читает серии (значение, длина), покрывающие ровно n клеток
+func (r *binaryReader) runs(n int, limit uint64, what string, set func(from, to int, v uint64)) {
+	for i := 0; i < n && r.err == nil; {
+		v := r.uvarint(limit, what+" value")
+		length := int(r.uvarint(uint64(n-i), what+" run"))
+		if r.err == nil && length == 0 {
+			r.fail("empty %s run", what)
+		}
+		if r.err == nil {
+			set(i, i+length, v)
+			i += length
+		}
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.fail("%d extra bytes in %s", len(r.data), what)
+	}
+}
+
+// DecodeBinaryMap декодирует карту из формата .grid, проверяя
+// контрольную сумму и согласованность всех секций
+func DecodeBinaryMap(data []byte) (*MapFile, error) {
+	if len(data) < len(binaryMagic)+1+4 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrCorruptGrid)
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptGrid)
+	}
+	if v := body[len(binaryMagic)]; v != binaryVersion {
+		return nil, fmt.Errorf("binary grid: unsupported version %d", v)
+	}
+
+	r := &binaryReader{data: body[len(binaryMagic)+1:]}
+	width := int(r.uvarint(maxBinaryCells, "width"))
+	height := int(r.uvarint(maxBinaryCells, "height"))
+	if r.err == nil && (width == 0 || height == 0 || width*height > maxBinaryCells) {
+		r.fail("unsupported size %dx%d", width, height)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	cells := width * height
+	m := &MapFile{Grid: NewGrid(width, height)}
+	grid := m.Grid
+
+	seen := make(map[byte]bool)
+	for len(r.data) > 0 && r.err == nil {
+		tag := r.bytes(1, "section tag")[0]
+		length := r.uvarint(uint64(len(r.data)), "section length")
+		payload := r.bytes(int(length), "section")
+		if r.err != nil {
+			break
+		}
+		if seen[tag] {
+			r.fail("duplicate section %q", tag)
+			break
+		}
+		seen[tag] = true
+
+		s := &binaryReader{data: payload}
+		switch tag {
+		case sectionObstacles:
+			decodeObstacles(s, grid.Obstacles)
+		case sectionCosts:
+			grid.Costs = decodeCosts(s, cells)
+		case sectionEndpoints:
+			m.Start, m.Goal = decodeEndpoints(s, grid)
+		case sectionComponents:
+			if !seen[sectionObstacles] {
+				s.fail("components before obstacles")
+				break
+			}
+			m.Components = decodeComponents(s, grid)
+		}
+		r.err = s.err
+	}
+	if r.err == nil && !seen[sectionObstacles] {
+		r.fail("missing obstacle section")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return m, nil
+}
+
+func decodeObstacles(r *binaryReader, obstacles []bool) {
+	obstacle := false
+	for i := 0; i < len(obstacles) && r.err == nil; {
+		length := int(r.uvarint(uint64(len(obstacles)-i), "obstacle run"))
+		if r.err == nil && length == 0 && (i > 0 || obstacle) {
+			r.fail("empty obstacle run")
+		}
+		for j := i; j < i+length; j++ {
+			obstacles[j] = obstacle
+		}
+		obstacle = !obstacle
+		i += length
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.fail("%d extra bytes in obstacles", len(r.data))
+	}
+}
+
+func decodeCosts(r *binaryReader, cells int) []float64 {
+	n := int(r.uvarint(maxCostLevels, "cost level count"))
+	raw := r.bytes(8*n, "cost levels")
+	if r.err == nil && n == 0 {
+		r.fail("no cost levels")
+	}
+	if r.err != nil {
+		return nil
+	}
+	levels := make([]float64, n)
+	for i := range levels {
+		levels[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[8*i:]))
+		if !validCost(levels[i]) {
+			r.fail("cost level %d is %g", i, levels[i])
+			return nil
+		}
+	}
+	costs := make([]float64, cells)
+	r.runs(cells, uint64(n-1), "cost", func(from, to int, v uint64) {
+		for i := from; i < to; i++ {
+			costs[i] = levels[v]
+		}
+	})
+	return costs
+}
+
+func decodeEndpoints(r *binaryReader, grid *Grid) (start, goal *Point) {
+	flags := r.bytes(1, "endpoint flags")
+	if r.err != nil {
+		return nil, nil
+	}
+	if flags[0]&^3 != 0 {
+		r.fail("unknown endpoint flags %#x", flags[0])
+		return nil, nil
+	}
+	var ends [2]*Point
+	for i := range ends {
+		if flags[0]&(1<<i) == 0 {
+			continue
+		}
+		x := int(r.uvarint(uint64(grid.Width-1), "endpoint x"))
+		y := int(r.uvarint(uint64(grid.Height-1), "endpoint y"))
+		ends[i] = &Point{x, y}
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.fail("%d extra bytes in endpoints", len(r.data))
+	}
+	return ends[0], ends[1]
+}
+
+// decodeComponents читает метки областей и проверяет, что они согласованы
+// с препятствиями: -1 ровно у препятствий. Связность областей не
+// проверяется - это стоило бы столько же, сколько их пересчет.
+func decodeComponents(r *binaryReader, grid *Grid) []int32 {
+	labels := make([]int32, len(grid.Obstacles))
+	r.runs(len(labels), math.MaxInt32, "component", func(from, to int, v uint64) {
+		for i := from; i < to; i++ {
+			labels[i] = int32(v) - 1
+		}
+	})
+	if r.err != nil {
+		return nil
+	}
+	for i, label := range labels {
+		if (label < 0) != grid.Obstacles[i] {
+			r.fail("component label %d at cell %d disagrees with obstacles", label, i)
+			return nil
+		}
+	}
+	return labels
+}
+
+// LoadBinaryMap читает карту в формате .grid
+func LoadBinaryMap(path string) (*MapFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := DecodeBinaryMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// WriteBinaryMap записывает карту в формате .grid
+func WriteBinaryMap(w io.Writer, m *MapFile, opts BinaryOptions) error {
+	data, err := EncodeBinaryMap(m, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
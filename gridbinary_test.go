@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+// binarySeeds - карты для затравки FuzzDecodeBinaryMap: без рельефа,
+// с рельефом, концами и областями связности
+func binarySeeds(t testing.TB) [][]byte {
+	plain := NewGrid(5, 3)
+	plain.FillRect(Point{1, 0}, Point{1, 1}, true)
+
+	weighted := NewGrid(8, 8)
+	weighted.DrawLine(Point{0, 4}, Point{6, 4}, true)
+	weighted.SetCost(Point{2, 2}, 3)
+	weighted.SetCost(Point{7, 7}, 1.5)
+
+	maps := []struct {
+		m    *MapFile
+		opts BinaryOptions
+	}{
+		{&MapFile{Grid: NewGrid(1, 1)}, BinaryOptions{}},
+		{&MapFile{Grid: plain}, BinaryOptions{}},
+		{&MapFile{Grid: plain, Start: &Point{0, 0}, Goal: &Point{4, 2}}, BinaryOptions{Components: true}},
+		{&MapFile{Grid: weighted, Goal: &Point{7, 0}}, BinaryOptions{Components: true}},
+	}
+	var seeds [][]byte
+	for _, s := range maps {
+		data, err := EncodeBinaryMap(s.m, s.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeds = append(seeds, data)
+	}
+	return seeds
+}
+
+// FuzzDecodeBinaryMap проверяет, что декодер не паникует на любых
+// данных, а принятая карта кодируется и декодируется в ту же карту.
+// Данные проверяются как есть и с исправленной контрольной суммой, иначе
+// почти все мутации отсекает проверка CRC.
+func FuzzDecodeBinaryMap(f *testing.F) {
+	for _, seed := range binarySeeds(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkBinaryRoundTrip(t, data)
+		if len(data) >= 4 {
+			body := data[:len(data)-4]
+			checkBinaryRoundTrip(t, binary.LittleEndian.AppendUint32(bytes.Clone(body), crc32.ChecksumIEEE(body)))
+		}
+	})
+}
+
+func checkBinaryRoundTrip(t *testing.T, data []byte) {
+	m, err := DecodeBinaryMap(data)
+	if err != nil {
+		return
+	}
+	encoded, err := EncodeBinaryMap(m, BinaryOptions{Components: m.Components != nil})
+	if err != nil {
+		t.Fatalf("encode a decoded map: %v", err)
+	}
+	again, err := DecodeBinaryMap(encoded)
+	if err != nil {
+		t.Fatalf("decode a re-encoded map: %v", err)
+	}
+	if !sameMapFile(m, again) {
+		t.Fatalf("round trip changed the map:\n%+v\n%+v", m, again)
+	}
+}
+
+func sameMapFile(a, b *MapFile) bool {
+	return a.Grid.Width == b.Grid.Width && a.Grid.Height == b.Grid.Height &&
+		reflect.DeepEqual(a.Grid.Obstacles, b.Grid.Obstacles) &&
+		reflect.DeepEqual(a.Grid.Costs, b.Grid.Costs) &&
+		reflect.DeepEqual(a.Start, b.Start) && reflect.DeepEqual(a.Goal, b.Goal) &&
+		reflect.DeepEqual(a.Components, b.Components)
+}
+
+func TestBinaryMapRoundTrip(t *testing.T) {
+	for i, data := range binarySeeds(t) {
+		m, err := DecodeBinaryMap(data)
+		if err != nil {
+			t.Fatalf("seed %d: %v", i, err)
+		}
+		encoded, err := EncodeBinaryMap(m, BinaryOptions{Components: m.Components != nil})
+		if err != nil {
+			t.Fatalf("seed %d: %v", i, err)
+		}
+		if !bytes.Equal(encoded, data) {
+			t.Errorf("seed %d: re-encoded map differs", i)
+		}
+	}
+}
+
+// TestGridUnmarshalBinarySubscribers проверяет, что подписчики узнают о
+// замене сетки, а сетку с подписчиками нельзя заменить сеткой другого
+// размера
+func TestGridUnmarshalBinarySubscribers(t *testing.T) {
+	src := NewGrid(4, 4)
+	src.AddObstacle(Point{1, 1})
+	src.SetCost(Point{2, 2}, 5)
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid := NewGrid(4, 4)
+	grid.AddObstacle(Point{3, 3})
+	var changes []GridChange
+	unsubscribe := grid.Subscribe(func(c GridChange) { changes = append(changes, c) })
+	if err := grid.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || len(changes[0].Cells) != 3 || changes[0].Version != grid.Version() {
+		t.Fatalf("got changes %+v, want one change of 3 cells at version %d", changes, grid.Version())
+	}
+	if d, _ := Diff(src, grid); !d.Empty() {
+		t.Errorf("grid differs from the decoded one: %+v", d.Cells)
+	}
+
+	other, err := NewGrid(2, 2).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := grid.UnmarshalBinary(other); err == nil || grid.Width != 4 {
+		t.Errorf("resize with subscribers: got %v and width %d, want an error and width 4", err, grid.Width)
+	}
+	unsubscribe()
+	if err := grid.UnmarshalBinary(other); err != nil || grid.Width != 2 {
+		t.Errorf("resize without subscribers: got %v and width %d, want width 2", err, grid.Width)
+	}
+}
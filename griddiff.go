@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Snapshot возвращает копию сетки, которая делит с ней массивы клеток до
+// первой правки любой из них (копирование при записи): снимок стоит
+// O(1) по памяти и времени. Копия получает текущую версию, но не
+// подписчиков. Копирование срабатывает только для правок через методы
+// сетки - прямые записи в Obstacles и Costs видны в обеих сетках.
+func (g *Grid) Snapshot() *Grid {
+	g.shared = true
+	return &Grid{
+		Width:     g.Width,
+		Height:    g.Height,
+		Obstacles: g.Obstacles,
+		Costs:     g.Costs,
+		version:   g.version,
+		shared:    true,
+	}
+}
+
+// unshare копирует массивы клеток, общие со снимком, перед правкой
+func (g *Grid) unshare() {
+	if !g.shared {
+		return
+	}
+	g.Obstacles = append([]bool(nil), g.Obstacles...)
+	if g.Costs != nil {
+		g.Costs = append([]float64(nil), g.Costs...)
+	}
+	g.shared = false
+}
+
+// GridDiff - разница между двумя сетками одного размера: измененные
+// клетки по возрастанию индекса с состоянием в исходной сетке и в новой
+type GridDiff struct {
+	Width, Height int
+	Cells         []CellChange
+}
+
+// Diff сравнивает сетки from и to одного размера
+func Diff(from, to *Grid) (*GridDiff, error) {
+	if from.Width != to.Width || from.Height != to.Height {
+		return nil, fmt.Errorf("diff: grid sizes differ: %dx%d and %dx%d", from.Width, from.Height, to.Width, to.Height)
+	}
+	d := &GridDiff{Width: from.Width, Height: from.Height}
+	for y := 0; y < from.Height; y++ {
+		for x := 0; x < from.Width; x++ {
+			p := Point{x, y}
+			c := CellChange{
+				Point:       p,
+				WasObstacle: from.IsObstacle(p),
+				Obstacle:    to.IsObstacle(p),
+				OldCost:     from.Cost(p),
+				Cost:        to.Cost(p),
+			}
+			if c.WasObstacle != c.Obstacle || c.OldCost != c.Cost {
+				d.Cells = append(d.Cells, c)
+			}
+		}
+	}
+	return d, nil
+}
+
+// Empty сообщает, что сетки совпадают
+func (d *GridDiff) Empty() bool {
+	return len(d.Cells) == 0
+}
+
+// Added возвращает клетки, ставшие препятствиями
+func (d *GridDiff) Added() []Point {
+	var points []Point
+	for _, c := range d.Cells {
+		if c.Obstacle && !c.WasObstacle {
+			points = append(points, c.Point)
+		}
+	}
+	return points
+}
+
+// Removed возвращает клетки, переставшие быть препятствиями
+func (d *GridDiff) Removed() []Point {
+	var points []Point
+	for _, c := range d.Cells {
+		if c.WasObstacle && !c.Obstacle {
+			points = append(points, c.Point)
+		}
+	}
+	return points
+}
+
+// CostChanges возвращает клетки, свободные в обеих сетках, с измененной
+// стоимостью; стоимость препятствия на поиск не влияет
+func (d *GridDiff) CostChanges() []CellChange {
+	var cells []CellChange
+	for _, c := range d.Cells {
+		if c.OldCost != c.Cost && !c.WasObstacle && !c.Obstacle {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+// Invert возвращает обратную разницу: от to к from
+func (d *GridDiff) Invert() *GridDiff {
+	inv := &GridDiff{Width: d.Width, Height: d.Height, Cells: make([]CellChange, len(d.Cells))}
+	for i, c := range d.Cells {
+		inv.Cells[i] = CellChange{
+			Point:       c.Point,
+			WasObstacle: c.Obstacle,
+			Obstacle:    c.WasObstacle,
+			OldCost:     c.Cost,
+			Cost:        c.OldCost,
+		}
+	}
+	return inv
+}
+
+// ErrPatchConflict - сетка не в том состоянии, от которого построена разница
+var ErrPatchConflict = errors.New("patch conflict")
+
+// Apply применяет разницу к сетке одной транзакцией Update. Каждая клетка
+// должна быть в исходном состоянии разницы, иначе сетка не меняется и
+// возвращается ErrPatchConflict.
+func (g *Grid) Apply(d *GridDiff) error {
+	if g.Width != d.Width || g.Height != d.Height {
+		return fmt.Errorf("apply: patch for a %dx%d grid, grid is %dx%d", d.Width, d.Height, g.Width, g.Height)
+	}
+	for _, c := range d.Cells {
+		if !g.InBounds(c.Point) {
+			return fmt.Errorf("apply: point (%d,%d) is outside the grid", c.Point.x, c.Point.y)
+		}
+		if g.IsObstacle(c.Point) != c.WasObstacle || g.Cost(c.Point) != c.OldCost {
+			return fmt.Errorf("%w: cell (%d,%d) was changed", ErrPatchConflict, c.Point.x, c.Point.y)
+		}
+	}
+	return g.Update(func() error {
+		for _, c := range d.Cells {
+			g.setObstacle(c.Point, c.Obstacle)
+			g.SetCost(c.Point, c.Cost)
+		}
+		return nil
+	})
+}
+
+// ErrNoHistory - отменять или повторять нечего
+var ErrNoHistory = errors.New("nothing to undo or redo")
+
+// UndoStack записывает правки сетки и отменяет или повторяет их. Шаг
+// истории - одно уведомление сетки: отдельный вызов AddObstacle, FillRect
+// и т.п. или целая транзакция Update. Новая правка очищает повторы.
+type UndoStack struct {
+	grid        *Grid
+	limit       int
+	undo, redo  []*GridDiff
+	replaying   bool
+	unsubscribe func()
+}
+
+// NewUndoStack подписывается на правки grid и хранит до limit шагов;
+// limit <= 0 - без ограничения
+func NewUndoStack(grid *Grid, limit int) *UndoStack {
+	u := &UndoStack{grid: grid, limit: limit}
+	u.unsubscribe = grid.Subscribe(u.record)
+	return u
+}
+
+func (u *UndoStack) record(change GridChange) {
+	if u.replaying {
+		return
+	}
+	u.undo = append(u.undo, &GridDiff{Width: u.grid.Width, Height: u.grid.Height, Cells: change.Cells})
+	if u.limit > 0 && len(u.undo) > u.limit {
+		u.undo = append(u.undo[:0], u.undo[len(u.undo)-u.limit:]...)
+	}
+	u.redo = u.redo[:0]
+}
+
+// CanUndo и CanRedo сообщают, есть ли шаги для отмены и повтора
+func (u *UndoStack) CanUndo() bool { return len(u.undo) > 0 }
+func (u *UndoStack) CanRedo() bool { return len(u.redo) > 0 }
+
+// Undo отменяет последний шаг
+func (u *UndoStack) Undo() error {
+	return u.replay(&u.undo, &u.redo, (*GridDiff).Invert)
+}
+
+// Redo повторяет последний отмененный шаг
+func (u *UndoStack) Redo() error {
+	return u.replay(&u.redo, &u.undo, func(d *GridDiff) *GridDiff { return d })
+}
+
+// replay применяет верхний шаг from и переносит его в to. При конфликте
+// (сетку правили в обход методов) шаг остается на месте.
+func (u *UndoStack) replay(from, to *[]*GridDiff, patch func(*GridDiff) *GridDiff) error {
+	if len(*from) == 0 {
+		return ErrNoHistory
+	}
+	d := (*from)[len(*from)-1]
+	u.replaying = true
+	err := u.grid.Apply(patch(d))
+	u.replaying = false
+	if err != nil {
+		return err
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, d)
+	return nil
+}
+
+// Clear забывает историю
+func (u *UndoStack) Clear() {
+	u.undo, u.redo = nil, nil
+}
+
+// Close отписывает историю от правок сетки
+func (u *UndoStack) Close() {
+	u.unsubscribe()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// sameCells сообщает, совпадают ли клетки сеток одного размера
+func sameCells(t *testing.T, a, b *Grid) bool {
+	t.Helper()
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.Empty()
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	grid := NewGrid(5, 5)
+	grid.AddObstacle(Point{1, 1})
+	grid.SetCost(Point{2, 2}, 3)
+	snap := grid.Snapshot()
+	if snap.Version() != grid.Version() || !sameCells(t, snap, grid) {
+		t.Fatal("snapshot differs from the grid")
+	}
+
+	grid.AddObstacle(Point{0, 0})
+	grid.SetCost(Point{2, 2}, 4)
+	if snap.IsObstacle(Point{0, 0}) || snap.Cost(Point{2, 2}) != 3 {
+		t.Error("grid edit leaked into the snapshot")
+	}
+	snap.RemoveObstacle(Point{1, 1})
+	if !grid.IsObstacle(Point{1, 1}) {
+		t.Error("snapshot edit leaked into the grid")
+	}
+
+	// Снимок снимка и сетка без массива стоимостей
+	plain := NewGrid(3, 3)
+	first := plain.Snapshot()
+	second := first.Snapshot()
+	plain.SetCost(Point{1, 1}, 2)
+	first.AddObstacle(Point{0, 0})
+	if first.Costs != nil || second.Costs != nil || second.IsObstacle(Point{0, 0}) || plain.IsObstacle(Point{0, 0}) {
+		t.Error("edits leaked between chained snapshots")
+	}
+}
+
+func TestSnapshotRollback(t *testing.T) {
+	grid := NewGrid(4, 4)
+	grid.SetCost(Point{1, 1}, 2)
+	snap := grid.Snapshot()
+	want := grid.Snapshot()
+
+	grid.Update(func() error {
+		grid.AddObstacle(Point{0, 0})
+		grid.SetCost(Point{1, 1}, 5)
+		return errors.New("rollback")
+	})
+	if !sameCells(t, grid, want) || !sameCells(t, snap, want) {
+		t.Error("rollback changed the grid or its snapshot")
+	}
+
+	// Снимок посреди транзакции видит незавершенную правку и не теряет
+	// ее при откате сетки
+	var inside *Grid
+	grid.Update(func() error {
+		grid.AddObstacle(Point{3, 3})
+		inside = grid.Snapshot()
+		grid.AddObstacle(Point{2, 2})
+		return errors.New("rollback")
+	})
+	if !sameCells(t, grid, want) {
+		t.Error("rollback after a snapshot left edits in the grid")
+	}
+	if !inside.IsObstacle(Point{3, 3}) || inside.IsObstacle(Point{2, 2}) {
+		t.Error("rollback changed a snapshot taken inside the transaction")
+	}
+}
+
+func TestGridApply(t *testing.T) {
+	from := NewGrid(4, 4)
+	from.SetCost(Point{2, 0}, 2)
+	to := from.Snapshot()
+	to.AddObstacle(Point{1, 1})
+	to.SetCost(Point{2, 0}, 6)
+	to.SetCost(Point{3, 3}, 2)
+	d, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid := from.Snapshot()
+	changes := recordChanges(grid)
+	if err := grid.Apply(d); err != nil {
+		t.Fatal(err)
+	}
+	if !sameCells(t, grid, to) || len(*changes) != 1 {
+		t.Errorf("apply: grid differs from the target or %d notifications, want 1", len(*changes))
+	}
+	if err := grid.Apply(d.Invert()); err != nil || !sameCells(t, grid, from) {
+		t.Errorf("inverted patch: %v", err)
+	}
+
+	// Одна клетка не в исходном состоянии - сетка не меняется вовсе
+	conflict := from.Snapshot()
+	conflict.SetCost(Point{3, 3}, 9)
+	before := conflict.Snapshot()
+	changes = recordChanges(conflict)
+	version := conflict.Version()
+	if err := conflict.Apply(d); !errors.Is(err, ErrPatchConflict) {
+		t.Fatalf("got %v, want ErrPatchConflict", err)
+	}
+	if !sameCells(t, conflict, before) || len(*changes) != 0 || conflict.Version() != version {
+		t.Error("conflicting patch changed the grid")
+	}
+
+	if err := NewGrid(3, 4).Apply(d); err == nil || errors.Is(err, ErrPatchConflict) {
+		t.Errorf("patch for another size: got %v, want a size error", err)
+	}
+}
+
+func TestUndoStack(t *testing.T) {
+	grid := NewGrid(5, 5)
+	u := NewUndoStack(grid, 0)
+	defer u.Close()
+	if err := u.Undo(); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("undo on an empty stack: got %v, want ErrNoHistory", err)
+	}
+
+	states := []*Grid{grid.Snapshot()}
+	edits := []func(){
+		func() { grid.AddObstacle(Point{0, 0}) },
+		func() { grid.FillRect(Point{1, 1}, Point{2, 2}, true) },
+		func() { grid.SetCost(Point{4, 4}, 7) },
+		func() {
+			grid.Update(func() error {
+				grid.RemoveObstacle(Point{1, 1})
+				grid.SetCost(Point{4, 4}, 2)
+				return nil
+			})
+		},
+	}
+	for _, edit := range edits {
+		edit()
+		states = append(states, grid.Snapshot())
+	}
+
+	for i := len(states) - 2; i >= 0; i-- {
+		if err := u.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		if !sameCells(t, grid, states[i]) {
+			t.Fatalf("undo to state %d: grid differs", i)
+		}
+	}
+	if u.CanUndo() || !u.CanRedo() {
+		t.Error("after undoing everything: want only redo")
+	}
+	for i := 1; i < len(states); i++ {
+		if err := u.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		if !sameCells(t, grid, states[i]) {
+			t.Fatalf("redo to state %d: grid differs", i)
+		}
+	}
+	if err := u.Redo(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("redo past the end: got %v, want ErrNoHistory", err)
+	}
+
+	// Новая правка очищает повторы
+	u.Undo()
+	grid.AddObstacle(Point{3, 0})
+	if u.CanRedo() {
+		t.Error("a new edit kept the redo history")
+	}
+
+	// Правка в обход методов - шаг остается в истории
+	grid.Obstacles[grid.index(Point{3, 0})] = false
+	if err := u.Undo(); !errors.Is(err, ErrPatchConflict) || !u.CanUndo() {
+		t.Errorf("undo after a direct write: got %v, want ErrPatchConflict with the step kept", err)
+	}
+}
+
+func TestUndoStackLimit(t *testing.T) {
+	grid := NewGrid(6, 1)
+	u := NewUndoStack(grid, 3)
+	for x := 0; x < 6; x++ {
+		grid.AddObstacle(Point{x, 0})
+	}
+	undone := 0
+	for u.Undo() == nil {
+		undone++
+	}
+	if undone != 3 {
+		t.Fatalf("undid %d steps, want the last 3", undone)
+	}
+	for x := 0; x < 6; x++ {
+		if grid.IsObstacle(Point{x, 0}) != (x < 3) {
+			t.Fatalf("cell %d: obstacle %v after undoing the last 3 steps", x, grid.IsObstacle(Point{x, 0}))
+		}
+	}
+
+	u.Clear()
+	u.Close()
+	grid.AddObstacle(Point{5, 0})
+	if u.CanUndo() || u.CanRedo() {
+		t.Error("closed stack recorded an edit")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import "errors"
+
+// CellChange - изменение клетки: состояние до правки и после нее
+type CellChange struct {
+	Point                 Point
+	WasObstacle, Obstacle bool
+	OldCost, Cost         float64
+}
+
+// GridChange - уведомление о правке сетки: новая версия и клетки, которые
+// правка действительно изменила, каждая по одному разу
+type GridChange struct {
+	Version uint64
+	Cells   []CellChange
+}
+
+type gridListener struct {
+	fn func(GridChange)
+}
+
+// gridEdit - незавершенная правка: вложенность begin/commit и прежние
+// состояния клеток в порядке изменения
+type gridEdit struct {
+	depth   int
+	changes []CellChange
+}
+
+// Subscribe подписывает fn на правки сетки, возвращает отмену подписки.
+// fn вызывается синхронно после каждой правки, изменившей клетки, и
+// получает их список; срез Cells принадлежит fn.
+func (g *Grid) Subscribe(fn func(GridChange)) (unsubscribe func()) {
+	l := &gridListener{fn}
+	g.listeners = append(g.listeners, l)
+	return func() {
+		for i, other := range g.listeners {
+			if other == l {
+				g.listeners = append(g.listeners[:i:i], g.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Update выполняет правки apply как одну транзакцию: версия растет один
+// раз, подписчики получают одно уведомление со всеми измененными
+// клетками. Если apply возвращает ошибку или паникует, клетки
+// возвращаются в прежнее состояние. Вложенные Update откатывают только
+// свои правки, уведомление отправляет внешний.
+func (g *Grid) Update(apply func() error) (err error) {
+	if apply == nil {
+		return errors.New("grid update: apply is nil")
+	}
+	mark := g.begin()
+	defer func() {
+		if r := recover(); r != nil {
+			g.rollback(mark)
+			g.commit()
+			panic(r)
+		}
+	}()
+	if err = apply(); err != nil {
+		g.rollback(mark)
+	}
+	g.commit()
+	return err
+}
+
+// begin открывает правку и возвращает отметку для отката
+func (g *Grid) begin() int {
+	g.edits.depth++
+	return len(g.edits.changes)
+}
+
+// record запоминает состояние клетки до ее изменения
+func (g *Grid) record(point Point) {
+	g.edits.changes = append(g.edits.changes, CellChange{
+		Point:       point,
+		WasObstacle: g.IsObstacle(point),
+		OldCost:     g.Cost(point),
+	})
+}
+
+// rollback возвращает клетки, измененные после отметки mark. Снимок мог
+// быть снят посреди правки, поэтому массивы сначала отделяются от него.
+func (g *Grid) rollback(mark int) {
+	changes := g.edits.changes
+	if len(changes) > mark {
+		g.unshare()
+	}
+	for i := len(changes) - 1; i >= mark; i-- {
+		c := changes[i]
+		g.Obstacles[g.index(c.Point)] = c.WasObstacle
+		if g.Costs != nil {
+			g.Costs[g.index(c.Point)] = c.OldCost
+		}
+	}
+	g.edits.changes = changes[:mark]
+}
+
+// commit закрывает правку; внешняя правка увеличивает версию и
+// уведомляет подписчиков, если клетки изменились
+func (g *Grid) commit() {
+	if g.edits.depth--; g.edits.depth > 0 {
+		return
+	}
+	changes := g.edits.changes
+	g.edits.changes = changes[:0]
+	if len(changes) == 1 && len(g.listeners) == 0 {
+		// одиночная правка без подписчиков - частый случай при загрузке карт
+		g.version++
+		return
+	}
+	merged := g.mergeChanges(changes)
+	if len(merged) == 0 {
+		return
+	}
+	g.version++
+	listeners := append([]*gridListener(nil), g.listeners...)
+	for i, l := range listeners {
+		cells := merged
+		if i < len(listeners)-1 {
+			cells = append([]CellChange(nil), merged...)
+		}
+		l.fn(GridChange{Version: g.version, Cells: cells})
+	}
+}
+
+// mergeChanges сводит записи к одной на клетку с состоянием до первой
+// правки и текущим, отбрасывая клетки, вернувшиеся в прежнее состояние.
+// Результат не делит память с changes.
+func (g *Grid) mergeChanges(changes []CellChange) []CellChange {
+	if len(changes) == 0 {
+		return nil
+	}
+	merged := make([]CellChange, 0, len(changes))
+	var seen map[Point]bool
+	if len(changes) > 1 {
+		seen = make(map[Point]bool, len(changes))
+	}
+	for _, c := range changes {
+		if seen != nil {
+			if seen[c.Point] {
+				continue
+			}
+			seen[c.Point] = true
+		}
+		c.Obstacle, c.Cost = g.IsObstacle(c.Point), g.Cost(c.Point)
+		if c.Obstacle != c.WasObstacle || c.Cost != c.OldCost {
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// FillRect делает препятствием (obstacle = true) или освобождает
+// прямоугольник с углами a и b включительно; часть вне сетки отсекается
+func (g *Grid) FillRect(a, b Point, obstacle bool) {
+	x0, x1 := max(min(a.x, b.x), 0), min(max(a.x, b.x), g.Width-1)
+	y0, y1 := max(min(a.y, b.y), 0), min(max(a.y, b.y), g.Height-1)
+	g.begin()
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			g.setObstacle(Point{x, y}, obstacle)
+		}
+	}
+	g.commit()
+}
+
+// DrawLine проводит линию Брезенхэма от from до to толщиной в клетку
+func (g *Grid) DrawLine(from, to Point, obstacle bool) {
+	g.begin()
+	bresenham(from, to, func(p Point) { g.setObstacle(p, obstacle) })
+	g.commit()
+}
+
+// DrawCircle проводит окружность радиуса radius с центром center
+// алгоритмом средней точки; радиус 0 - одна клетка
+func (g *Grid) DrawCircle(center Point, radius int, obstacle bool) {
+	if radius < 0 {
+		return
+	}
+	g.begin()
+	plot := func(dx, dy int) {
+		for _, p := range [...]Point{
+			{center.x + dx, center.y + dy}, {center.x - dx, center.y + dy},
+			{center.x + dx, center.y - dy}, {center.x - dx, center.y - dy},
+			{center.x + dy, center.y + dx}, {center.x - dy, center.y + dx},
+			{center.x + dy, center.y - dx}, {center.x - dy, center.y - dx},
+		} {
+			g.setObstacle(p, obstacle)
+		}
+	}
+	x, y, d := radius, 0, 1-radius
+	for y <= x {
+		plot(x, y)
+		y++
+		if d < 0 {
+			d += 2*y + 1
+		} else {
+			x--
+			d += 2*(y-x) + 1
+		}
+	}
+	g.commit()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// recordChanges подписывается на правки сетки и копит уведомления
+func recordChanges(grid *Grid) *[]GridChange {
+	var changes []GridChange
+	grid.Subscribe(func(c GridChange) { changes = append(changes, c) })
+	return &changes
+}
+
+func TestGridUpdateTransaction(t *testing.T) {
+	grid := NewGrid(4, 4)
+	grid.AddObstacle(Point{3, 3})
+	changes := recordChanges(grid)
+	version := grid.Version()
+
+	err := grid.Update(func() error {
+		grid.AddObstacle(Point{0, 0})
+		grid.SetCost(Point{1, 0}, 2)
+		grid.SetCost(Point{1, 0}, 3) // та же клетка - одна запись
+		grid.AddObstacle(Point{2, 0})
+		grid.RemoveObstacle(Point{2, 0}) // вернулась - без записи
+		grid.RemoveObstacle(Point{3, 3})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CellChange{
+		{Point: Point{0, 0}, WasObstacle: false, Obstacle: true, OldCost: 1, Cost: 1},
+		{Point: Point{1, 0}, OldCost: 1, Cost: 3},
+		{Point: Point{3, 3}, WasObstacle: true, Obstacle: false, OldCost: 1, Cost: 1},
+	}
+	if len(*changes) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(*changes))
+	}
+	if c := (*changes)[0]; c.Version != version+1 || !reflect.DeepEqual(c.Cells, want) {
+		t.Errorf("got %+v, want version %d and cells %+v", c, version+1, want)
+	}
+	if grid.Version() != version+1 {
+		t.Errorf("version %d, want %d", grid.Version(), version+1)
+	}
+
+	// Правка, ничего не изменившая, не увеличивает версию и не уведомляет
+	grid.Update(func() error {
+		grid.AddObstacle(Point{0, 0})
+		return nil
+	})
+	if len(*changes) != 1 || grid.Version() != version+1 {
+		t.Errorf("no-op update: %d notifications, version %d", len(*changes), grid.Version())
+	}
+}
+
+func TestGridUpdateRollback(t *testing.T) {
+	grid := NewGrid(4, 4)
+	grid.SetCost(Point{1, 1}, 5)
+	changes := recordChanges(grid)
+	version := grid.Version()
+	before := grid.Snapshot()
+
+	errApply := errors.New("apply failed")
+	err := grid.Update(func() error {
+		grid.AddObstacle(Point{0, 0})
+		grid.SetCost(Point{1, 1}, 2)
+		grid.SetCost(Point{2, 2}, 7) // массив стоимостей уже есть
+		return errApply
+	})
+	if !errors.Is(err, errApply) {
+		t.Fatalf("got %v, want %v", err, errApply)
+	}
+	if d, _ := Diff(before, grid); !d.Empty() {
+		t.Errorf("cells not rolled back: %+v", d.Cells)
+	}
+	if len(*changes) != 0 || grid.Version() != version {
+		t.Errorf("rolled back update: %d notifications, version %d, want none and %d", len(*changes), grid.Version(), version)
+	}
+	if err := grid.Update(nil); err == nil {
+		t.Error("Update(nil) returned no error")
+	}
+}
+
+func TestGridUpdateNested(t *testing.T) {
+	grid := NewGrid(4, 4)
+	changes := recordChanges(grid)
+
+	err := grid.Update(func() error {
+		grid.AddObstacle(Point{0, 0})
+		inner := grid.Update(func() error {
+			grid.AddObstacle(Point{1, 0})
+			grid.RemoveObstacle(Point{0, 0}) // откатывается и эта правка внешней клетки
+			return errors.New("inner")
+		})
+		if inner == nil {
+			t.Error("inner update lost its error")
+		}
+		if len(*changes) != 0 {
+			t.Error("inner update notified listeners")
+		}
+		grid.AddObstacle(Point{2, 0})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*changes) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(*changes))
+	}
+	var points []Point
+	for _, c := range (*changes)[0].Cells {
+		points = append(points, c.Point)
+	}
+	if want := []Point{{0, 0}, {2, 0}}; !reflect.DeepEqual(points, want) {
+		t.Errorf("changed cells %v, want %v", points, want)
+	}
+	if grid.IsObstacle(Point{1, 0}) || !grid.IsObstacle(Point{0, 0}) {
+		t.Error("inner rollback restored the wrong cells")
+	}
+}
+
+func TestGridUpdatePanic(t *testing.T) {
+	grid := NewGrid(4, 4)
+	changes := recordChanges(grid)
+	version := grid.Version()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the original panic", r)
+			}
+		}()
+		grid.Update(func() error {
+			grid.AddObstacle(Point{0, 0})
+			panic("boom")
+		})
+	}()
+	if grid.IsObstacle(Point{0, 0}) || len(*changes) != 0 || grid.Version() != version {
+		t.Fatal("panicking update was not rolled back")
+	}
+	// Правка после паники снова уведомляет: вложенность сброшена
+	grid.AddObstacle(Point{1, 1})
+	if len(*changes) != 1 || grid.Version() != version+1 {
+		t.Errorf("edit after a panic: %d notifications, version %d", len(*changes), grid.Version())
+	}
+}
+
+func TestGridSingleEdit(t *testing.T) {
+	grid := NewGrid(3, 3)
+	grid.AddObstacle(Point{0, 0})
+	grid.AddObstacle(Point{0, 0})
+	grid.AddObstacle(Point{5, 5})
+	if grid.Version() != 1 {
+		t.Errorf("without listeners: version %d, want 1", grid.Version())
+	}
+
+	changes := recordChanges(grid)
+	grid.RemoveObstacle(Point{0, 0})
+	want := GridChange{Version: 2, Cells: []CellChange{{Point: Point{0, 0}, WasObstacle: true, OldCost: 1, Cost: 1}}}
+	if len(*changes) != 1 || !reflect.DeepEqual((*changes)[0], want) {
+		t.Errorf("got %+v, want %+v", *changes, want)
+	}
+}
+
+func TestGridSetCostRejects(t *testing.T) {
+	grid := NewGrid(2, 2)
+	for _, cost := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), -1, 0, 0.5} {
+		grid.SetCost(Point{0, 0}, cost)
+	}
+	if grid.Costs != nil || grid.Version() != 0 {
+		t.Errorf("invalid costs were written: %v, version %d", grid.Costs, grid.Version())
+	}
+	grid.SetCost(Point{0, 0}, 1e300)
+	if grid.Cost(Point{0, 0}) != 1e300 {
+		t.Errorf("cost %g, want 1e300", grid.Cost(Point{0, 0}))
+	}
+}
+
+func TestGridSubscribers(t *testing.T) {
+	grid := NewGrid(3, 3)
+	var a, b []GridChange
+	unsubscribeA := grid.Subscribe(func(c GridChange) {
+		c.Cells[0].Cost = -1 // срез принадлежит подписчику
+		a = append(a, c)
+	})
+	grid.Subscribe(func(c GridChange) { b = append(b, c) })
+
+	grid.SetCost(Point{1, 1}, 4)
+	if len(a) != 1 || len(b) != 1 || b[0].Cells[0].Cost != 4 {
+		t.Fatalf("listeners share cells: %+v, %+v", a, b)
+	}
+	unsubscribeA()
+	grid.SetCost(Point{1, 1}, 5)
+	if len(a) != 1 || len(b) != 2 {
+		t.Errorf("after unsubscribe: %d and %d notifications, want 1 and 2", len(a), len(b))
+	}
+}
+
+func TestGridShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		draw  func(g *Grid)
+		cells int
+	}{
+		{"rect", func(g *Grid) { g.FillRect(Point{1, 1}, Point{3, 2}, true) }, 6},
+		{"rect clipped", func(g *Grid) { g.FillRect(Point{-5, -5}, Point{1, 0}, true) }, 2},
+		{"line", func(g *Grid) { g.DrawLine(Point{0, 0}, Point{6, 3}, true) }, 10}, // 4-связная линия: dx+dy+1
+		{"line clipped", func(g *Grid) { g.DrawLine(Point{-2, 0}, Point{2, 0}, true) }, 3},
+		{"circle", func(g *Grid) { g.DrawCircle(Point{3, 3}, 2, true) }, 12},
+		{"circle radius 0", func(g *Grid) { g.DrawCircle(Point{3, 3}, 0, true) }, 1},
+		{"circle negative", func(g *Grid) { g.DrawCircle(Point{3, 3}, -1, true) }, 0},
+	}
+	for _, tt := range tests {
+		grid := NewGrid(7, 7)
+		changes := recordChanges(grid)
+		tt.draw(grid)
+		obstacles := 0
+		for _, o := range grid.Obstacles {
+			if o {
+				obstacles++
+			}
+		}
+		if obstacles != tt.cells {
+			t.Errorf("%s: %d obstacles, want %d", tt.name, obstacles, tt.cells)
+		}
+		wantNotes := 1
+		if tt.cells == 0 {
+			wantNotes = 0
+		}
+		if len(*changes) != wantNotes || (wantNotes == 1 && len((*changes)[0].Cells) != tt.cells) {
+			t.Errorf("%s: notifications %+v, want %d with %d cells", tt.name, *changes, wantNotes, tt.cells)
+		}
+	}
+}
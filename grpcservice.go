@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"astar/pathrpc"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pathfinderService - gRPC-сервис pathrpc поверх сеток HTTP-сервиса:
+// сетки, загруженные через HTTP, доступны и по gRPC
+type pathfinderService struct {
+	server *Server
+}
+
+// NewPathfinderService создает реализацию gRPC-сервиса для сеток server
+func NewPathfinderService(server *Server) pathrpc.PathfinderServer {
+	return &pathfinderService{server: server}
+}
+
+func (p *pathfinderService) grid(name string) (*serverGrid, error) {
+	g := p.server.gridByName(name)
+	if g == nil {
+		return nil, status.Errorf(codes.NotFound, "grid %q not found", name)
+	}
+	return g, nil
+}
+
+func (p *pathfinderService) FindPath(ctx context.Context, in *pathrpc.FindPathRequest) (*pathrpc.PathResponse, error) {
+	g, err := p.grid(in.Grid)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.server.findPath(ctx, g, pathRequestFromRPC(in), nil)
+	if err := rpcError(err); err != nil {
+		return nil, err
+	}
+	return result.rpc(), nil
+}
+
+// streamBuffer - сколько событий StreamSearch ждут отправки; события
+// сверх этого отбрасываются, чтобы медленный клиент не задерживал поиск
+// под блокировкой сетки
+const streamBuffer = 256
+
+func (p *pathfinderService) StreamSearch(in *pathrpc.StreamSearchRequest, stream pathrpc.StreamSearchServer) error {
+	g, err := p.grid(in.Grid)
+	if err != nil {
+		return err
+	}
+	every := max(1, in.Every)
+
+	// Поиск идет под блокировкой сетки на чтение, поэтому события
+	// отправляет отдельная горутина из очереди, а наблюдатель только
+	// кладет их туда без ожидания. Ошибка отправки прерывает поиск через
+	// отмену контекста.
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	events := make(chan *pathrpc.SearchEvent, streamBuffer)
+	done := make(chan struct{})
+	var sendErr error
+	go func() {
+		defer close(done)
+		for ev := range events {
+			if sendErr != nil {
+				continue
+			}
+			if sendErr = stream.Send(ev); sendErr != nil {
+				cancel()
+			}
+		}
+	}()
+
+	expanded, dropped := 0, 0
+	onPop := func(n *Node) {
+		expanded++
+		if (expanded-1)%every != 0 {
+			return
+		}
+		ev := &pathrpc.SearchEvent{
+			Expanded: expanded,
+			Point:    pathrpc.Point{n.Position.x, n.Position.y},
+			G:        n.GCost,
+			H:        n.HCost,
+			F:        n.FCost,
+		}
+		select {
+		case events <- ev:
+		default:
+			dropped++
+		}
+	}
+
+	result, err := p.server.findPath(ctx, g, pathRequestFromRPC(&in.FindPathRequest), ObserverFuncs{Pop: onPop})
+	close(events)
+	<-done
+	if sendErr != nil {
+		return sendErr
+	}
+	if err := rpcError(err); err != nil {
+		return err
+	}
+	return stream.Send(&pathrpc.SearchEvent{Expanded: expanded, Dropped: dropped, Result: result.rpc()})
+}
+
+func (p *pathfinderService) UpdateGrid(ctx context.Context, in *pathrpc.UpdateGridRequest) (*pathrpc.GridInfo, error) {
+	g, err := p.grid(in.Grid)
+	if err != nil {
+		return nil, err
+	}
+	edits := gridEdits{Add: in.Add, Remove: in.Remove}
+	for _, c := range in.Costs {
+		edits.Costs = append(edits.Costs, cellCost{Point: c.Point, Cost: c.Cost})
+	}
+	if err := g.edit(edits); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	info := g.info(in.Grid)
+	return &pathrpc.GridInfo{
+		Name:      info.Name,
+		Width:     info.Width,
+		Height:    info.Height,
+		Obstacles: info.Obstacles,
+		Weighted:  info.Weighted,
+		Start:     info.Start,
+		Goal:      info.Goal,
+		Version:   info.Version,
+	}, nil
+}
+
+func pathRequestFromRPC(in *pathrpc.FindPathRequest) pathRequest {
+	return pathRequest{
+		Start:     in.Start,
+		Goal:      in.Goal,
+		Algorithm: in.Algorithm,
+		Weight:    in.Weight,
+		Heuristic: in.Heuristic,
+		Movement:  in.Movement,
+		TimeoutMS: in.TimeoutMS,
+	}
+}
+
+// rpc переводит результат в сообщение pathrpc
+func (r solveResult) rpc() *pathrpc.PathResponse {
+	return &pathrpc.PathResponse{
+		Found:     r.Found,
+		Error:     r.Error,
+		Start:     r.Start,
+		Goal:      r.Goal,
+		Steps:     r.Steps,
+		Cost:      r.Cost,
+		Path:      r.Path,
+		Stats:     pathrpc.SearchStats(r.Stats),
+		ElapsedMS: r.ElapsedMS,
+	}
+}
+
+// rpcError переводит ошибку findPath в статус gRPC. Отсутствие пути -
+// не ошибка вызова: оно сообщается в ответе.
+func rpcError(err error) error {
+	var badRequest requestError
+	switch {
+	case err == nil, errors.Is(err, ErrNoPath):
+		return nil
+	case errors.As(err, &badRequest), errors.Is(err, ErrInvalidPoint):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
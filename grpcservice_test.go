@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"astar/pathrpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestRPC поднимает gRPC-сервис над srv в памяти и возвращает клиента
+func newTestRPC(t *testing.T, srv *Server) *pathrpc.Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	pathrpc.RegisterPathfinderServer(gs, NewPathfinderService(srv))
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return pathrpc.NewClient(cc)
+}
+
+func newTestRPCServer(t *testing.T) *Server {
+	t.Helper()
+	srv := NewServer(ServerOptions{})
+	m, err := ParseASCII(strings.NewReader(testServerMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddGrid("m", m)
+	return srv
+}
+
+func TestRPCFindPath(t *testing.T) {
+	client := newTestRPC(t, newTestRPCServer(t))
+	ctx := context.Background()
+
+	resp, err := client.FindPath(ctx, &pathrpc.FindPathRequest{Grid: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Found || resp.Steps != 5 || resp.Goal != (pathrpc.Point{3, 2}) {
+		t.Errorf("FindPath: got %+v, want a 5-step path to (3,2)", resp)
+	}
+
+	tests := []struct {
+		name string
+		req  *pathrpc.FindPathRequest
+		want codes.Code
+	}{
+		{"unknown grid", &pathrpc.FindPathRequest{Grid: "other"}, codes.NotFound},
+		{"bad algorithm", &pathrpc.FindPathRequest{Grid: "m", Algorithm: "nope"}, codes.InvalidArgument},
+		{"outside", &pathrpc.FindPathRequest{Grid: "m", Goal: &pathrpc.Point{10, 10}}, codes.InvalidArgument},
+		{"wall", &pathrpc.FindPathRequest{Grid: "m", Goal: &pathrpc.Point{1, 1}}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		if _, err := client.FindPath(ctx, tt.req); status.Code(err) != tt.want {
+			t.Errorf("%s: got %v, want code %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRPCUpdateGrid(t *testing.T) {
+	client := newTestRPC(t, newTestRPCServer(t))
+	ctx := context.Background()
+
+	info, err := client.UpdateGrid(ctx, &pathrpc.UpdateGridRequest{Grid: "m", Add: []pathrpc.Point{{1, 0}, {1, 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Obstacles != 4 || info.Version != 1 {
+		t.Errorf("UpdateGrid: got %d obstacles at version %d, want 4 at version 1", info.Obstacles, info.Version)
+	}
+	resp, err := client.FindPath(ctx, &pathrpc.FindPathRequest{Grid: "m"})
+	if err != nil || resp.Found {
+		t.Errorf("FindPath after UpdateGrid: got %+v, %v; want no path", resp, err)
+	}
+
+	_, err = client.UpdateGrid(ctx, &pathrpc.UpdateGridRequest{Grid: "m", Add: []pathrpc.Point{{4, 0}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateGrid outside the grid: got %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+// readStream читает поток до конца и возвращает число событий раскрытия
+// и итоговое событие
+func readStream(t *testing.T, stream *pathrpc.SearchStream) (int, *pathrpc.SearchEvent) {
+	t.Helper()
+	var events int
+	var last *pathrpc.SearchEvent
+	for {
+		ev, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return events, last
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Result == nil {
+			events++
+		}
+		last = ev
+	}
+}
+
+func TestRPCStreamSearch(t *testing.T) {
+	client := newTestRPC(t, newTestRPCServer(t))
+	stream, err := client.StreamSearch(context.Background(), &pathrpc.StreamSearchRequest{
+		FindPathRequest: pathrpc.FindPathRequest{Grid: "m"},
+		Every:           2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, last := readStream(t, stream)
+	if last == nil || last.Result == nil || !last.Result.Found {
+		t.Fatalf("StreamSearch: last event %+v, want a found path", last)
+	}
+	want := (last.Result.Stats.Expanded + 1) / 2
+	if events != want || last.Dropped != 0 {
+		t.Errorf("StreamSearch: %d events, %d dropped; want %d events", events, last.Dropped, want)
+	}
+}
+
+// TestRPCStreamSearchSlowReader проверяет, что клиент, который не читает
+// поток, не держит сетку: поиск завершается, правка проходит, а события
+// сверх очереди отбрасываются
+func TestRPCStreamSearchSlowReader(t *testing.T) {
+	srv := NewServer(ServerOptions{})
+	srv.AddGrid("open", &MapFile{Grid: NewGrid(300, 300), Start: &Point{0, 0}, Goal: &Point{299, 299}})
+	client := newTestRPC(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := client.StreamSearch(ctx, &pathrpc.StreamSearchRequest{
+		FindPathRequest: pathrpc.FindPathRequest{Grid: "open", Algorithm: "dijkstra"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Событий больше, чем вмещают очередь и окно HTTP/2: без очереди поиск
+	// встал бы на отправке, держа сетку на чтение
+	time.Sleep(200 * time.Millisecond)
+	editCtx, editCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer editCancel()
+	if _, err := client.UpdateGrid(editCtx, &pathrpc.UpdateGridRequest{Grid: "open", Add: []pathrpc.Point{{5, 5}}}); err != nil {
+		t.Fatalf("UpdateGrid while a stream is not read: %v", err)
+	}
+
+	events, last := readStream(t, stream)
+	if last == nil || last.Result == nil || !last.Result.Found {
+		t.Fatalf("StreamSearch: last event %+v, want a found path", last)
+	}
+	if last.Dropped == 0 {
+		t.Errorf("StreamSearch: no events dropped for a slow reader")
+	}
+	if events+last.Dropped != last.Result.Stats.Expanded {
+		t.Errorf("StreamSearch: %d events + %d dropped, want %d expansions", events, last.Dropped, last.Result.Stats.Expanded)
+	}
+}
+
+// TestRPCCodecName проверяет, что пакет регистрирует кодек под своим
+// именем и не занимает общий для процесса подтип "json"
+func TestRPCCodecName(t *testing.T) {
+	if encoding.GetCodec(pathrpc.CodecName) == nil {
+		t.Fatalf("codec %q isn't registered", pathrpc.CodecName)
+	}
+	if c := encoding.GetCodec("json"); c != nil {
+		t.Errorf("content subtype \"json\" is taken by %T", c)
+	}
+}
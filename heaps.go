@@ -0,0 +1,201 @@
+package main
+
+// DaryHeap - d-арная куча без упаковки в interface{}.
+// При d=4 дерево ниже, а просеивание вниз лучше использует кэш.
+type DaryHeap struct {
+	d     int
+	nodes []*Node
+}
+
+func NewDaryHeap(d int) *DaryHeap {
+	if d < 2 {
+		d = 2
+	}
+	return &DaryHeap{d: d}
+}
+
+func (h *DaryHeap) Len() int {
+	return len(h.nodes)
+}
+
+func (h *DaryHeap) Push(node *Node) {
+	node.Index = len(h.nodes)
+	h.nodes = append(h.nodes, node)
+	h.up(node.Index)
+}
+
+func (h *DaryHeap) Pop() *Node {
+	top := h.nodes[0]
+	last := len(h.nodes) - 1
+	h.swap(0, last)
+	h.nodes[last] = nil
+	h.nodes = h.nodes[:last]
+	if last > 0 {
+		h.down(0)
+	}
+	top.Index = -1
+	return top
+}
+
+func (h *DaryHeap) Update(node *Node) {
+	h.up(node.Index)
+}
+
+func (h *DaryHeap) Reset() {
+	clear(h.nodes)
+	h.nodes = h.nodes[:0]
+}
+
+func (h *DaryHeap) swap(i, j int) {
+	h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i]
+	h.nodes[i].Index = i
+	h.nodes[j].Index = j
+}
+
+func (h *DaryHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / h.d
+		if h.nodes[parent].FCost <= h.nodes[i].FCost {
+			return
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+func (h *DaryHeap) down(i int) {
+	n := len(h.nodes)
+	for {
+		first := i*h.d + 1
+		if first >= n {
+			return
+		}
+		best := first
+		for c := first + 1; c < first+h.d && c < n; c++ {
+			if h.nodes[c].FCost < h.nodes[best].FCost {
+				best = c
+			}
+		}
+		if h.nodes[i].FCost <= h.nodes[best].FCost {
+			return
+		}
+		h.swap(i, best)
+		i = best
+	}
+}
+
+// ===================================================== //
+// Парная куча (pairing heap) //
+
+// pairElem - элемент парной кучи; ссылки - индексы в PairingHeap.elems, -1 - нет.
+// prev указывает на родителя у первого ребенка и на левого брата у остальных.
+type pairElem struct {
+	node                 *Node
+	child, sibling, prev int32
+}
+
+// PairingHeap - парная куча с уменьшением ключа за O(1).
+// Node.Index хранит индекс элемента в elems.
+type PairingHeap struct {
+	elems   []pairElem
+	root    int32
+	size    int
+	scratch []int32
+}
+
+func (h *PairingHeap) Len() int {
+	return h.size
+}
+
+func (h *PairingHeap) Push(node *Node) {
+	i := int32(len(h.elems))
+	h.elems = append(h.elems, pairElem{node: node, child: -1, sibling: -1, prev: -1})
+	node.Index = int(i)
+	h.root = h.meld(h.root, i)
+	h.size++
+}
+
+func (h *PairingHeap) Pop() *Node {
+	root := h.root
+	top := h.elems[root].node
+
+	// Первый проход: сливаем детей попарно слева направо
+	h.scratch = h.scratch[:0]
+	for c := h.elems[root].child; c >= 0; {
+		a := c
+		b := h.elems[a].sibling
+		if b < 0 {
+			h.detach(a)
+			h.scratch = append(h.scratch, a)
+			break
+		}
+		c = h.elems[b].sibling
+		h.detach(a)
+		h.detach(b)
+		h.scratch = append(h.scratch, h.meld(a, b))
+	}
+
+	// Второй проход: сливаем результаты справа налево
+	merged := int32(-1)
+	for i := len(h.scratch) - 1; i >= 0; i-- {
+		merged = h.meld(merged, h.scratch[i])
+	}
+
+	h.elems[root].child = -1
+	h.root = merged
+	h.size--
+	top.Index = -1
+	return top
+}
+
+func (h *PairingHeap) Update(node *Node) {
+	i := int32(node.Index)
+	if i == h.root {
+		return
+	}
+	// Вырезаем поддерево и сливаем его с корнем
+	e := &h.elems[i]
+	if h.elems[e.prev].child == i {
+		h.elems[e.prev].child = e.sibling
+	} else {
+		h.elems[e.prev].sibling = e.sibling
+	}
+	if e.sibling >= 0 {
+		h.elems[e.sibling].prev = e.prev
+	}
+	e.sibling, e.prev = -1, -1
+	h.root = h.meld(h.root, i)
+}
+
+func (h *PairingHeap) Reset() {
+	clear(h.elems)
+	h.elems = h.elems[:0]
+	h.root = -1
+	h.size = 0
+}
+
+func (h *PairingHeap) detach(i int32) {
+	h.elems[i].sibling, h.elems[i].prev = -1, -1
+}
+
+// meld сливает две кучи и возвращает новый корень
+func (h *PairingHeap) meld(a, b int32) int32 {
+	if a < 0 {
+		return b
+	}
+	if b < 0 {
+		return a
+	}
+	if h.elems[b].node.FCost < h.elems[a].node.FCost {
+		a, b = b, a
+	}
+	// b становится первым ребенком a
+	ea, eb := &h.elems[a], &h.elems[b]
+	eb.sibling = ea.child
+	if ea.child >= 0 {
+		h.elems[ea.child].prev = b
+	}
+	eb.prev = a
+	ea.child = b
+	return a
+}
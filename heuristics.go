@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Heuristic оценивает стоимость пути между двумя точками
+type Heuristic func(point1, point2 Point) float64
+
+// Octile - точная оценка для 8-связного движения с диагональю sqrt(2)
+func Octile(point1, point2 Point) float64 {
+	dx := math.Abs(float64(point1.x - point2.x))
+	dy := math.Abs(float64(point1.y - point2.y))
+	return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+}
+
+// Euclidean - расстояние по прямой
+func Euclidean(point1, point2 Point) float64 {
+	return math.Hypot(float64(point1.x-point2.x), float64(point1.y-point2.y))
+}
+
+// Chebyshev - оценка для 8-связного движения с диагональю стоимостью 1
+func Chebyshev(point1, point2 Point) float64 {
+	return math.Max(math.Abs(float64(point1.x-point2.x)), math.Abs(float64(point1.y-point2.y)))
+}
+
+// Movement - модель перемещения между клетками
+type Movement int
+
+const (
+	Moves4 Movement = iota // только по горизонтали и вертикали
+	Moves8                 // плюс диагонали без срезания углов препятствий
+)
+
+// directions8 - смещения для 8-связного движения, диагонали в конце
+var directions8 = [8][2]int{
+	{0, 1}, {0, -1}, {1, 0}, {-1, 0},
+	{1, 1}, {1, -1}, {-1, 1}, {-1, -1},
+}
+
+// directions возвращает допустимые смещения модели
+func (m Movement) directions() [][2]int {
+	if m == Moves8 {
+		return directions8[:]
+	}
+	return directions4[:]
+}
+
+// DefaultHeuristic - допустимая эвристика по умолчанию для модели движения
+func (m Movement) DefaultHeuristic() Heuristic {
+	if m == Moves8 {
+		return Octile
+	}
+	return HeuristicFunc
+}
+
+// Zero - нулевая эвристика: A* вырождается в алгоритм Дейкстры
+func Zero(point1, point2 Point) float64 {
+	return 0
+}
+
+// heuristicNames - эвристики, доступные по имени в сценариях и CLI
+var heuristicNames = map[string]Heuristic{
+	"manhattan": HeuristicFunc,
+	"octile":    Octile,
+	"euclidean": Euclidean,
+	"chebyshev": Chebyshev,
+	"zero":      Zero,
+}
+
+// ParseHeuristic находит эвристику по имени
+func ParseHeuristic(name string) (Heuristic, error) {
+	h, ok := heuristicNames[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown heuristic %q (want manhattan, octile, euclidean, chebyshev or zero)", name)
+	}
+	return h, nil
+}
+
+// ParseMovement разбирает модель движения: "4" или "8"
+func ParseMovement(name string) (Movement, error) {
+	switch name {
+	case "4":
+		return Moves4, nil
+	case "8":
+		return Moves8, nil
+	}
+	return 0, fmt.Errorf("unknown movement %q (want 4 or 8)", name)
+}
+
+func (m Movement) String() string {
+	if m == Moves8 {
+		return "8"
+	}
+	return "4"
+}
+
+// Algorithm - вариант поиска по лучшему первому
+type Algorithm int
+
+const (
+	AlgorithmAStar    Algorithm = iota // f = g + h
+	AlgorithmDijkstra                  // f = g, эвристика не используется
+	AlgorithmGreedy                    // f = h, быстро, но без гарантии оптимальности
+	AlgorithmWeighted                  // f = g + w*h, путь не длиннее w * оптимального
+)
+
+var algorithmNames = [...]string{
+	AlgorithmAStar:    "astar",
+	AlgorithmDijkstra: "dijkstra",
+	AlgorithmGreedy:   "greedy",
+	AlgorithmWeighted: "weighted",
+}
+
+func (a Algorithm) String() string {
+	if a < 0 || int(a) >= len(algorithmNames) {
+		return fmt.Sprintf("Algorithm(%d)", int(a))
+	}
+	return algorithmNames[a]
+}
+
+// ParseAlgorithm находит вариант поиска по имени
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for i, n := range algorithmNames {
+		if n == name {
+			return Algorithm(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown algorithm %q (want astar, dijkstra, greedy or weighted)", name)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+)
+
+// ColorCost сопоставляет цвет на изображении стоимости клетки.
+// Cost = +Inf означает препятствие; стоимость клетки меньше 1 Grid не
+// принимает, такая клетка стоит 1.
+type ColorCost struct {
+	Color     color.Color
+	Cost      float64
+	Tolerance uint8 // допустимое отклонение по каждому каналу
+}
+
+// ImageGridOptions - параметры преобразования изображения в сетку и обратно
+type ImageGridOptions struct {
+	// Threshold - яркость (0-255), ниже которой пиксель считается препятствием.
+	// 0 - значение по умолчанию 128.
+	Threshold uint8
+	// CellSize - сторона клетки в пикселях; блок CellSize x CellSize
+	// сворачивается в одну клетку. 0 - один пиксель на клетку.
+	CellSize int
+	// ObstacleFraction - доля пикселей-препятствий в блоке, начиная с которой
+	// клетка становится препятствием. 0 - значение по умолчанию 0.5.
+	ObstacleFraction float64
+	// Terrain - таблица цветов рельефа; проверяется раньше порога яркости.
+	// При экспорте клетки с совпадающей стоимостью рисуются этими цветами.
+	Terrain []ColorCost
+}
+
+func (o ImageGridOptions) withDefaults() ImageGridOptions {
+	if o.Threshold == 0 {
+		o.Threshold = 128
+	}
+	if o.CellSize <= 0 {
+		o.CellSize = 1
+	}
+	if o.ObstacleFraction <= 0 {
+		o.ObstacleFraction = 0.5
+	}
+	return o
+}
+
+// pixelCost возвращает стоимость пикселя, +Inf - препятствие
+func (o ImageGridOptions) pixelCost(c color.Color) float64 {
+	r, g, b, a := c.RGBA()
+	if a < 0x8000 {
+		return 1 // прозрачные пиксели считаются свободными
+	}
+	for _, t := range o.Terrain {
+		if colorsClose(c, t.Color, t.Tolerance) {
+			return t.Cost
+		}
+	}
+	luma := (19595*r + 38470*g + 7471*b + 1<<15) >> 24
+	if uint8(luma) < o.Threshold {
+		return math.Inf(1)
+	}
+	return 1
+}
+
+func colorsClose(a, b color.Color, tolerance uint8) bool {
+	ca := color.NRGBAModel.Convert(a).(color.NRGBA)
+	cb := color.NRGBAModel.Convert(b).(color.NRGBA)
+	diff := func(x, y uint8) uint8 {
+		if x > y {
+			return x - y
+		}
+		return y - x
+	}
+	return diff(ca.R, cb.R) <= tolerance && diff(ca.G, cb.G) <= tolerance && diff(ca.B, cb.B) <= tolerance
+}
+
+// GridFromImage строит сетку по изображению. Клетка становится препятствием,
+// если доля пикселей-препятствий в ее блоке не меньше ObstacleFraction;
+// иначе ее стоимость - среднее по проходимым пикселям блока.
+func GridFromImage(img image.Image, opts ImageGridOptions) *Grid {
+	opts = opts.withDefaults()
+	bounds := img.Bounds()
+	size := opts.CellSize
+	width := (bounds.Dx() + size - 1) / size
+	height := (bounds.Dy() + size - 1) / size
+
+	grid := NewGrid(width, height)
+	for cy := 0; cy < height; cy++ {
+		for cx := 0; cx < width; cx++ {
+			blocked, passable, total := 0, 0.0, 0
+			for py := bounds.Min.Y + cy*size; py < bounds.Min.Y+(cy+1)*size && py < bounds.Max.Y; py++ {
+				for px := bounds.Min.X + cx*size; px < bounds.Min.X+(cx+1)*size && px < bounds.Max.X; px++ {
+					total++
+					cost := opts.pixelCost(img.At(px, py))
+					if math.IsInf(cost, 1) {
+						blocked++
+					} else {
+						passable += cost
+					}
+				}
+			}
+
+			p := Point{cx, cy}
+			if float64(blocked) >= opts.ObstacleFraction*float64(total) {
+				grid.AddObstacle(p)
+			} else {
+				grid.SetCost(p, passable/float64(total-blocked))
+			}
+		}
+	}
+	return grid
+}
+
+// LoadGridImage читает PNG, GIF или BMP и строит по нему сетку
+func LoadGridImage(path string, opts ImageGridOptions) (*Grid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return GridFromImage(img, opts), nil
+}
+
+// Цвета попиксельного экспорта
+var (
+	imageFreeColor     = color.RGBA{255, 255, 255, 255}
+	imageObstacleColor = color.RGBA{0, 0, 0, 255}
+	imagePathColor     = color.RGBA{0, 0, 255, 255}
+)
+
+// cellColor возвращает цвет клетки при экспорте. Рельеф без записи
+// в таблице рисуется оттенками серого светлее порога препятствий.
+func (o ImageGridOptions) cellColor(grid *Grid, p Point) color.Color {
+	if grid.IsObstacle(p) {
+		return imageObstacleColor
+	}
+	cost := grid.Cost(p)
+	for _, t := range o.Terrain {
+		if t.Cost == cost {
+			return t.Color
+		}
+	}
+	if cost <= 1 {
+		return imageFreeColor
+	}
+	span := 255 - int(o.Threshold) - 8
+	shade := 255 - int(math.Min(cost-1, 8)/8*float64(span))
+	return color.RGBA{uint8(shade), uint8(shade), uint8(shade), 255}
+}
+
+// GridToImage рисует сетку попиксельно: каждая клетка - квадрат CellSize
+// пикселей, без осей и подписей. Клетки пути закрашиваются синим.
+func GridToImage(grid *Grid, path []*Node, opts ImageGridOptions) *image.RGBA {
+	opts = opts.withDefaults()
+	size := opts.CellSize
+	img := image.NewRGBA(image.Rect(0, 0, grid.Width*size, grid.Height*size))
+
+	fill := func(p Point, c color.Color) {
+		r := image.Rect(p.x*size, p.y*size, (p.x+1)*size, (p.y+1)*size)
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			fill(Point{x, y}, opts.cellColor(grid, Point{x, y}))
+		}
+	}
+	for _, node := range path {
+		fill(node.Position, imagePathColor)
+	}
+	return img
+}
+
+// SaveGridImage сохраняет попиксельное изображение сетки;
+// формат определяется расширением: .png, .gif или .bmp
+func SaveGridImage(filename string, grid *Grid, path []*Node, opts ImageGridOptions) error {
+	img := GridToImage(grid, path, opts)
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".png":
+		err = png.Encode(f, img)
+	case ".gif":
+		err = gif.Encode(f, exactPaletted(img), nil)
+	case ".bmp":
+		err = bmp.Encode(f, img)
+	default:
+		err = fmt.Errorf("unsupported image format %q", ext)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// exactPaletted переводит изображение в палитровое без потери цветов,
+// если их не больше 256; иначе квантование остается за кодировщиком GIF
+func exactPaletted(img *image.RGBA) image.Image {
+	index := make(map[color.RGBA]uint8)
+	var pal color.Palette
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, nil)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			i, ok := index[c]
+			if !ok {
+				if len(pal) == 256 {
+					return img
+				}
+				i = uint8(len(pal))
+				index[c] = i
+				pal = append(pal, c)
+			}
+			paletted.SetColorIndex(x, y, i)
+		}
+	}
+	paletted.Palette = pal
+	return paletted
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MapFile - сетка, прочитанная из файла, с метками старта и цели.
+// Start и Goal равны nil, если формат их не задает.
+// Scenario заполнен для карт, загруженных из сценария, Components - для
+// двоичных карт с сохраненными областями связности.
+type MapFile struct {
+	Grid        *Grid
+	Start, Goal *Point
+	Scenario    *Scenario
+	Components  []int32 // метки Components, если файл их хранит
+}
+
+// maxMapCells ограничивает размер сетки, заявленный в файле, чтобы
+// поврежденный заголовок не приводил к огромному выделению памяти
+const maxMapCells = 1 << 28
+
+// checkGridSize проверяет заявленный размер сетки до ее выделения
+func checkGridSize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("grid size must be positive, got %dx%d", width, height)
+	}
+	if width > maxMapCells/height {
+		return fmt.Errorf("grid %dx%d exceeds the limit of %d cells", width, height, maxMapCells)
+	}
+	return nil
+}
+
+// LoadMap читает карту, формат определяется расширением:
+// .txt - текстовый формат, .map - MovingAI, .grid - двоичный формат,
+// .png/.gif/.bmp - изображение, .json/.yaml/.yml - сценарий (сетка и
+// первый запрос)
+func LoadMap(path string, imageOpts ImageGridOptions) (*MapFile, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt", ".ascii":
+		return LoadASCII(path)
+	case ".grid":
+		return LoadBinaryMap(path)
+	case ".map":
+		grid, err := LoadMovingAIMap(path)
+		if err != nil {
+			return nil, err
+		}
+		return &MapFile{Grid: grid}, nil
+	case ".png", ".gif", ".bmp":
+		grid, err := LoadGridImage(path, imageOpts)
+		if err != nil {
+			return nil, err
+		}
+		return &MapFile{Grid: grid}, nil
+	case ".json", ".yaml", ".yml":
+		sc, err := LoadScenario(path)
+		if err != nil {
+			return nil, err
+		}
+		return sc.MapFile(), nil
+	}
+	return nil, fmt.Errorf("%s: unknown map format %q", path, filepath.Ext(path))
+}
+
+// SaveMap записывает карту, формат определяется расширением:
+// .txt - текстовый формат, .map - MovingAI, .grid - двоичный формат
+// с областями связности, .png/.gif/.bmp - изображение
+func SaveMap(path string, m *MapFile, imageOpts ImageGridOptions) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".png" || ext == ".gif" || ext == ".bmp" {
+		return SaveGridImage(path, m.Grid, nil, imageOpts)
+	}
+
+	var write func(io.Writer) error
+	switch ext {
+	case ".txt", ".ascii":
+		write = func(w io.Writer) error { return WriteASCII(w, m.Grid, m.Start, m.Goal) }
+	case ".map":
+		write = func(w io.Writer) error { return WriteMovingAIMap(w, m.Grid) }
+	case ".grid":
+		write = func(w io.Writer) error { return WriteBinaryMap(w, m, BinaryOptions{Components: true}) }
+	default:
+		return fmt.Errorf("%s: unknown map format %q", path, ext)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// maze - лабиринт на решетке логических клеток cols x rows.
+// east[i] и south[i] отмечают проходы от клетки i к правому и нижнему соседу.
+type maze struct {
+	cols, rows  int
+	east, south []bool
+	rng         *rand.Rand
+}
+
+func newMaze(cols, rows int, rng *rand.Rand) *maze {
+	return &maze{
+		cols:  cols,
+		rows:  rows,
+		east:  make([]bool, cols*rows),
+		south: make([]bool, cols*rows),
+		rng:   rng,
+	}
+}
+
+// neighbors возвращает соседние клетки в фиксированном порядке
+func (m *maze) neighbors(i int, buf []int) []int {
+	buf = buf[:0]
+	x, y := i%m.cols, i/m.cols
+	if y > 0 {
+		buf = append(buf, i-m.cols)
+	}
+	if y < m.rows-1 {
+		buf = append(buf, i+m.cols)
+	}
+	if x > 0 {
+		buf = append(buf, i-1)
+	}
+	if x < m.cols-1 {
+		buf = append(buf, i+1)
+	}
+	return buf
+}
+
+// link прорубает проход между соседними клетками a и b
+func (m *maze) link(a, b int) {
+	if a > b {
+		a, b = b, a
+	}
+	if b == a+1 {
+		m.east[a] = true
+	} else {
+		m.south[a] = true
+	}
+}
+
+// linked сообщает, есть ли проход между соседними клетками a и b
+func (m *maze) linked(a, b int) bool {
+	if a > b {
+		a, b = b, a
+	}
+	if b == a+1 {
+		return m.east[a]
+	}
+	return m.south[a]
+}
+
+// degree - число проходов из клетки
+func (m *maze) degree(i int, buf []int) int {
+	d := 0
+	for _, n := range m.neighbors(i, buf) {
+		if m.linked(i, n) {
+			d++
+		}
+	}
+	return d
+}
+
+// backtracker - рекурсивный поиск с возвратом на явном стеке
+func (m *maze) backtracker() {
+	visited := make([]bool, m.cols*m.rows)
+	start := m.rng.Intn(len(visited))
+	stack := []int{start}
+	visited[start] = true
+
+	var buf, candidates []int
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		candidates = candidates[:0]
+		for _, n := range m.neighbors(current, buf) {
+			if !visited[n] {
+				candidates = append(candidates, n)
+			}
+		}
+		if len(candidates) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		next := candidates[m.rng.Intn(len(candidates))]
+		m.link(current, next)
+		visited[next] = true
+		stack = append(stack, next)
+	}
+}
+
+// prim - рандомизированный алгоритм Прима по клеткам
+func (m *maze) prim() {
+	const (
+		outside = iota
+		frontier
+		inside
+	)
+	state := make([]uint8, m.cols*m.rows)
+	var front, buf, candidates []int
+
+	add := func(i int) {
+		state[i] = inside
+		for _, n := range m.neighbors(i, buf) {
+			if state[n] == outside {
+				state[n] = frontier
+				front = append(front, n)
+			}
+		}
+	}
+	add(m.rng.Intn(len(state)))
+
+	for len(front) > 0 {
+		k := m.rng.Intn(len(front))
+		cell := front[k]
+		front[k] = front[len(front)-1]
+		front = front[:len(front)-1]
+
+		candidates = candidates[:0]
+		for _, n := range m.neighbors(cell, buf) {
+			if state[n] == inside {
+				candidates = append(candidates, n)
+			}
+		}
+		m.link(cell, candidates[m.rng.Intn(len(candidates))])
+		add(cell)
+	}
+}
+
+// kruskal - рандомизированный алгоритм Краскала с системой непересекающихся множеств
+func (m *maze) kruskal() {
+	parent := make([]int, m.cols*m.rows)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	type edge struct{ a, b int }
+	var edges []edge
+	for i := range parent {
+		if i%m.cols < m.cols-1 {
+			edges = append(edges, edge{i, i + 1})
+		}
+		if i/m.cols < m.rows-1 {
+			edges = append(edges, edge{i, i + m.cols})
+		}
+	}
+	m.rng.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+
+	for _, e := range edges {
+		ra, rb := find(e.a), find(e.b)
+		if ra != rb {
+			parent[ra] = rb
+			m.link(e.a, e.b)
+		}
+	}
+}
+
+// wilson - алгоритм Уилсона: случайные блуждания со стиранием петель,
+// дает равномерно распределенное остовное дерево
+func (m *maze) wilson() {
+	size := m.cols * m.rows
+	inTree := make([]bool, size)
+	next := make([]int, size)
+	inTree[m.rng.Intn(size)] = true
+
+	var buf []int
+	for start := 0; start < size; start++ {
+		if inTree[start] {
+			continue
+		}
+		// Блуждаем до дерева; перезапись next стирает петли
+		for cell := start; !inTree[cell]; cell = next[cell] {
+			ns := m.neighbors(cell, buf)
+			next[cell] = ns[m.rng.Intn(len(ns))]
+		}
+		for cell := start; !inTree[cell]; cell = next[cell] {
+			inTree[cell] = true
+			m.link(cell, next[cell])
+		}
+	}
+}
+
+// eller - алгоритм Эллера: строка за строкой с отслеживанием множеств
+func (m *maze) eller() {
+	sets := make([]int, m.cols)
+	nextSet := 1
+	var members []int
+
+	for y := 0; y < m.rows; y++ {
+		row := y * m.cols
+		for x := range sets {
+			if sets[x] == 0 {
+				sets[x] = nextSet
+				nextSet++
+			}
+		}
+
+		// Случайно объединяем соседние множества; в последней строке - все
+		last := y == m.rows-1
+		for x := 0; x < m.cols-1; x++ {
+			if sets[x] == sets[x+1] || (!last && m.rng.Intn(2) == 0) {
+				continue
+			}
+			m.link(row+x, row+x+1)
+			old := sets[x+1]
+			for k := range sets {
+				if sets[k] == old {
+					sets[k] = sets[x]
+				}
+			}
+		}
+		if last {
+			break
+		}
+
+		// Каждое множество спускается вниз хотя бы одной клеткой
+		below := make([]int, m.cols)
+		done := make(map[int]bool)
+		for x := 0; x < m.cols; x++ {
+			set := sets[x]
+			if done[set] {
+				continue
+			}
+			done[set] = true
+
+			members = members[:0]
+			for k := x; k < m.cols; k++ {
+				if sets[k] == set {
+					members = append(members, k)
+				}
+			}
+			m.rng.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+			for i, k := range members {
+				if i == 0 || m.rng.Intn(2) == 0 {
+					m.link(row+k, row+k+m.cols)
+					below[k] = set
+				}
+			}
+		}
+		sets = below
+	}
+}
+
+// braid удаляет тупики с вероятностью factor, добавляя петли.
+// Предпочтение отдается проходу в соседний тупик.
+func (m *maze) braid(factor float64) {
+	if factor <= 0 {
+		return
+	}
+	var buf, ns, candidates []int
+	for _, i := range m.rng.Perm(m.cols * m.rows) {
+		if m.degree(i, buf) != 1 || m.rng.Float64() >= factor {
+			continue
+		}
+		ns = append(ns[:0], m.neighbors(i, buf)...)
+		candidates = candidates[:0]
+		for _, n := range ns {
+			if !m.linked(i, n) && m.degree(n, buf) == 1 {
+				candidates = append(candidates, n)
+			}
+		}
+		if len(candidates) == 0 {
+			for _, n := range ns {
+				if !m.linked(i, n) {
+					candidates = append(candidates, n)
+				}
+			}
+		}
+		if len(candidates) > 0 {
+			m.link(i, candidates[m.rng.Intn(len(candidates))])
+		}
+	}
+}
+
+// render переносит лабиринт на сетку: клетки и проходы шириной corridor,
+// стены толщиной в одну клетку. Лишние ряды справа и снизу остаются стенами.
+func (m *maze) render(width, height, corridor int) *Grid {
+	grid := NewGrid(width, height)
+	for i := range grid.Obstacles {
+		grid.Obstacles[i] = true
+	}
+	carve := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				grid.Obstacles[grid.index(Point{x, y})] = false
+			}
+		}
+	}
+
+	step := corridor + 1
+	for i := 0; i < m.cols*m.rows; i++ {
+		x0, y0 := 1+(i%m.cols)*step, 1+(i/m.cols)*step
+		carve(x0, y0, corridor, corridor)
+		if m.east[i] {
+			carve(x0+corridor, y0, 1, corridor)
+		}
+		if m.south[i] {
+			carve(x0, y0+corridor, corridor, 1)
+		}
+	}
+	return grid
+}
+
+// mazeGenerator оборачивает алгоритм построения лабиринта в Generator.
+// Старт - левая верхняя клетка, цель - правая нижняя.
+func mazeGenerator(build func(*maze)) Generator {
+	return func(opts GenerateOptions) (*MapFile, error) {
+		corridor := max(opts.CorridorWidth, 1)
+		if opts.Braid < 0 || opts.Braid > 1 {
+			return nil, fmt.Errorf("braid must be within [0, 1], got %g", opts.Braid)
+		}
+		cols, rows := (opts.Width-1)/(corridor+1), (opts.Height-1)/(corridor+1)
+		if cols < 1 || rows < 1 {
+			return nil, fmt.Errorf("map %dx%d is too small for corridor width %d", opts.Width, opts.Height, corridor)
+		}
+
+		m := newMaze(cols, rows, rand.New(rand.NewSource(opts.Seed)))
+		build(m)
+		m.braid(opts.Braid)
+
+		grid := m.render(opts.Width, opts.Height, corridor)
+		start, goal := opts.endpoints(Point{1, 1},
+			Point{(cols-1)*(corridor+1) + corridor, (rows-1)*(corridor+1) + corridor})
+		return opts.finish(grid, start, goal)
+	}
+}
+
+// Процедурные лабиринты; все гарантированно связны
+var (
+	GenerateMazeBacktracker = mazeGenerator((*maze).backtracker)
+	GenerateMazePrim        = mazeGenerator((*maze).prim)
+	GenerateMazeKruskal     = mazeGenerator((*maze).kruskal)
+	GenerateMazeWilson      = mazeGenerator((*maze).wilson)
+	GenerateMazeEller       = mazeGenerator((*maze).eller)
+)
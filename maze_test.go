@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMazeConnected проверяет, что каждый алгоритм лабиринта на разных
+// размерах, зернах, ширинах коридоров и с петлями и без дает одну
+// связную область, а концы лежат в ней
+func TestMazeConnected(t *testing.T) {
+	algorithms := map[string]Generator{
+		"backtracker": GenerateMazeBacktracker,
+		"prim":        GenerateMazePrim,
+		"kruskal":     GenerateMazeKruskal,
+		"wilson":      GenerateMazeWilson,
+		"eller":       GenerateMazeEller,
+	}
+	sizes := [][2]int{{5, 5}, {21, 13}, {40, 40}, {64, 33}}
+	for name, generate := range algorithms {
+		for _, size := range sizes {
+			for _, corridor := range []int{1, 2} {
+				for _, braid := range []float64{0, 0.5, 1} {
+					for seed := int64(1); seed <= 3; seed++ {
+						opts := GenerateOptions{
+							Width: size[0], Height: size[1], Seed: seed,
+							CorridorWidth: corridor, Braid: braid,
+						}
+						label := fmt.Sprintf("%s/%dx%d/corridor%d/braid%g/seed%d", name, size[0], size[1], corridor, braid, seed)
+						m, err := generate(opts)
+						if err != nil {
+							t.Errorf("%s: %v", label, err)
+							continue
+						}
+						labels, count := Components(m.Grid)
+						if count != 1 {
+							t.Errorf("%s: %d regions, want 1", label, count)
+							continue
+						}
+						for _, end := range []*Point{m.Start, m.Goal} {
+							if end != nil && labels[m.Grid.index(*end)] != 0 {
+								t.Errorf("%s: endpoint (%d,%d) is not in the maze", label, end.x, end.y)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestMazeDeterministic проверяет, что одинаковое зерно дает одинаковый
+// лабиринт
+func TestMazeDeterministic(t *testing.T) {
+	opts := GenerateOptions{Width: 31, Height: 21, Seed: 42, Braid: 0.3}
+	a, err := GenerateMazeWilson(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateMazeWilson(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Diff(a.Grid, b.Grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("same seed produced %d different cells", len(d.Cells))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SearchOutcome - итог поиска для метрик
+type SearchOutcome int
+
+const (
+	OutcomeFound     SearchOutcome = iota // путь найден
+	OutcomeNoPath                         // пути нет
+	OutcomeInvalid                        // старт или цель вне сетки или на препятствии
+	OutcomeCancelled                      // контекст отменен или истек
+	OutcomeError                          // прочие ошибки
+	outcomeCount
+)
+
+var outcomeNames = [...]string{
+	OutcomeFound:     "found",
+	OutcomeNoPath:    "no_path",
+	OutcomeInvalid:   "invalid_endpoints",
+	OutcomeCancelled: "cancelled",
+	OutcomeError:     "error",
+}
+
+func (o SearchOutcome) String() string {
+	if o < 0 || o >= outcomeCount {
+		return fmt.Sprintf("SearchOutcome(%d)", int(o))
+	}
+	return outcomeNames[o]
+}
+
+// OutcomeOf классифицирует ошибку поиска
+func OutcomeOf(err error) SearchOutcome {
+	switch {
+	case err == nil:
+		return OutcomeFound
+	case errors.Is(err, ErrNoPath):
+		return OutcomeNoPath
+	case errors.Is(err, ErrInvalidPoint):
+		return OutcomeInvalid
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeCancelled
+	}
+	return OutcomeError
+}
+
+// histogram - гистограмма с фиксированными верхними границами корзин
+type histogram struct {
+	bounds []float64
+	counts []uint64 // по корзине на границу и последняя - +Inf
+	sum    float64
+}
+
+func newHistogram(bounds ...float64) histogram {
+	return histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// exponentialBounds возвращает n границ start, start*factor, ...
+func exponentialBounds(start, factor float64, n int) []float64 {
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = start
+		start *= factor
+	}
+	return bounds
+}
+
+func (h *histogram) observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += v
+}
+
+// SearchMetrics собирает метрики поисков и отдает их в текстовом формате
+// OpenMetrics: число запросов по итогам, гистограммы времени поиска,
+// раскрытых узлов и наибольшего размера открытого списка, число поисков
+// в работе. Безопасен для одновременного использования.
+type SearchMetrics struct {
+	mu       sync.Mutex
+	queries  [outcomeCount]uint64
+	inFlight int64
+	duration histogram // секунды
+	expanded histogram
+	maxOpen  histogram
+}
+
+// NewSearchMetrics создает пустой набор метрик
+func NewSearchMetrics() *SearchMetrics {
+	return &SearchMetrics{
+		duration: newHistogram(exponentialBounds(0.0001, 4, 9)...), // 100 мкс .. 6.5 с
+		expanded: newHistogram(exponentialBounds(10, 10, 7)...),    // 10 .. 10^7
+		maxOpen:  newHistogram(exponentialBounds(10, 10, 6)...),    // 10 .. 10^6
+	}
+}
+
+// Observe учитывает завершенный поиск по его статистике, времени и ошибке.
+// Запросы с недопустимыми концами в гистограммы не попадают: поиска не было.
+func (m *SearchMetrics) Observe(stats SearchStats, elapsed time.Duration, err error) {
+	outcome := OutcomeOf(err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries[outcome]++
+	if outcome == OutcomeInvalid {
+		return
+	}
+	m.duration.observe(elapsed.Seconds())
+	m.expanded.observe(float64(stats.Expanded))
+	m.maxOpen.observe(float64(stats.MaxOpen))
+}
+
+// Search выполняет s.SearchContext и учитывает его в метриках
+func (m *SearchMetrics) Search(ctx context.Context, s *Searcher, start, goal Point) ([]*Node, error) {
+	m.mu.Lock()
+	m.inFlight++
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.mu.Unlock()
+	}()
+
+	begin := time.Now()
+	path, err := s.SearchContext(ctx, start, goal)
+	m.Observe(s.Stats(), time.Since(begin), err)
+	return path, err
+}
+
+// WriteOpenMetrics выводит метрики в текстовом формате OpenMetrics 1.0
+func (m *SearchMetrics) WriteOpenMetrics(w io.Writer) error {
+	m.mu.Lock()
+	queries, inFlight := m.queries, m.inFlight
+	duration, expanded, maxOpen := m.duration.clone(), m.expanded.clone(), m.maxOpen.clone()
+	m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# TYPE astar_queries counter")
+	fmt.Fprintln(bw, "# HELP astar_queries Searches by outcome.")
+	for o, n := range queries {
+		fmt.Fprintf(bw, "astar_queries_total{outcome=%q} %d\n", SearchOutcome(o).String(), n)
+	}
+	fmt.Fprintln(bw, "# TYPE astar_searches_in_flight gauge")
+	fmt.Fprintln(bw, "# HELP astar_searches_in_flight Searches currently running.")
+	fmt.Fprintf(bw, "astar_searches_in_flight %d\n", inFlight)
+	duration.write(bw, "astar_search_duration_seconds", "seconds", "Search wall time.")
+	expanded.write(bw, "astar_search_expanded_nodes", "", "Nodes expanded per search.")
+	maxOpen.write(bw, "astar_search_open_list_max", "", "Largest open list size per search.")
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+func (h histogram) clone() histogram {
+	h.counts = append([]uint64(nil), h.counts...)
+	return h
+}
+
+// write выводит гистограмму с накопленными счетчиками корзин
+func (h histogram) write(w io.Writer, name, unit, help string) {
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	if unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", name, unit)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	var total uint64
+	for i, bound := range h.bounds {
+		total += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), total)
+	}
+	total += h.counts[len(h.bounds)]
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, total)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, total)
+}
+
+// ServeHTTP отдает метрики для сборщика Prometheus или совместимого
+func (m *SearchMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	m.WriteOpenMetrics(w)
+}
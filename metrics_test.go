@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutcomeOf(t *testing.T) {
+	grid := NewGrid(3, 3)
+	grid.FillRect(Point{1, 0}, Point{1, 2}, true)
+	s := NewSearcher(grid)
+	search := func(ctx context.Context, start, goal Point) error {
+		_, err := s.SearchContext(ctx, start, goal)
+		return err
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		err  error
+		want SearchOutcome
+	}{
+		{"found", search(context.Background(), Point{0, 0}, Point{0, 2}), OutcomeFound},
+		{"no path", search(context.Background(), Point{0, 0}, Point{2, 2}), OutcomeNoPath},
+		{"start outside", search(context.Background(), Point{-1, 0}, Point{0, 2}), OutcomeInvalid},
+		{"goal on an obstacle", search(context.Background(), Point{0, 0}, Point{1, 1}), OutcomeInvalid},
+		{"cancelled", search(cancelled, Point{0, 0}, Point{0, 2}), OutcomeCancelled},
+		{"deadline", search(expired, Point{0, 0}, Point{0, 2}), OutcomeCancelled},
+		{"wrapped no path", fmt.Errorf("query 3: %w", ErrNoPath), OutcomeNoPath},
+		{"search limit", ErrSearchLimit, OutcomeError},
+		{"other", errors.New("disk full"), OutcomeError},
+	}
+	for _, tt := range tests {
+		if got := OutcomeOf(tt.err); got != tt.want {
+			t.Errorf("%s: OutcomeOf(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+	if s := SearchOutcome(42).String(); s != "SearchOutcome(42)" {
+		t.Errorf("unknown outcome prints as %q", s)
+	}
+}
+
+const metricsGolden = `# TYPE astar_queries counter
+# HELP astar_queries Searches by outcome.
+astar_queries_total{outcome="found"} 1
+astar_queries_total{outcome="no_path"} 1
+astar_queries_total{outcome="invalid_endpoints"} 1
+astar_queries_total{outcome="cancelled"} 0
+astar_queries_total{outcome="error"} 0
+# TYPE astar_searches_in_flight gauge
+# HELP astar_searches_in_flight Searches currently running.
+astar_searches_in_flight 0
+# TYPE astar_search_duration_seconds histogram
+# UNIT astar_search_duration_seconds seconds
+# HELP astar_search_duration_seconds Search wall time.
+astar_search_duration_seconds_bucket{le="0.0001"} 0
+astar_search_duration_seconds_bucket{le="0.0004"} 0
+astar_search_duration_seconds_bucket{le="0.0016"} 0
+astar_search_duration_seconds_bucket{le="0.0064"} 1
+astar_search_duration_seconds_bucket{le="0.0256"} 1
+astar_search_duration_seconds_bucket{le="0.1024"} 1
+astar_search_duration_seconds_bucket{le="0.4096"} 1
+astar_search_duration_seconds_bucket{le="1.6384"} 2
+astar_search_duration_seconds_bucket{le="6.5536"} 2
+astar_search_duration_seconds_bucket{le="+Inf"} 2
+astar_search_duration_seconds_sum 0.502
+astar_search_duration_seconds_count 2
+# TYPE astar_search_expanded_nodes histogram
+# HELP astar_search_expanded_nodes Nodes expanded per search.
+astar_search_expanded_nodes_bucket{le="10"} 1
+astar_search_expanded_nodes_bucket{le="100"} 1
+astar_search_expanded_nodes_bucket{le="1000"} 2
+astar_search_expanded_nodes_bucket{le="10000"} 2
+astar_search_expanded_nodes_bucket{le="100000"} 2
+astar_search_expanded_nodes_bucket{le="1e+06"} 2
+astar_search_expanded_nodes_bucket{le="1e+07"} 2
+astar_search_expanded_nodes_bucket{le="+Inf"} 2
+astar_search_expanded_nodes_sum 160
+astar_search_expanded_nodes_count 2
+# TYPE astar_search_open_list_max histogram
+# HELP astar_search_open_list_max Largest open list size per search.
+astar_search_open_list_max_bucket{le="10"} 1
+astar_search_open_list_max_bucket{le="100"} 2
+astar_search_open_list_max_bucket{le="1000"} 2
+astar_search_open_list_max_bucket{le="10000"} 2
+astar_search_open_list_max_bucket{le="100000"} 2
+astar_search_open_list_max_bucket{le="1e+06"} 2
+astar_search_open_list_max_bucket{le="+Inf"} 2
+astar_search_open_list_max_sum 43
+astar_search_open_list_max_count 2
+# EOF
+`
+
+// TestWriteOpenMetrics сверяет вывод с эталоном: счетчик с суффиксом
+// _total, единица длительности, накопленные корзины (граница входит в
+// свою корзину) и завершающий # EOF
+func TestWriteOpenMetrics(t *testing.T) {
+	m := NewSearchMetrics()
+	m.Observe(SearchStats{Expanded: 10, MaxOpen: 3}, 2*time.Millisecond, nil)
+	m.Observe(SearchStats{Expanded: 150, MaxOpen: 40}, 500*time.Millisecond, fmt.Errorf("%w: test", ErrNoPath))
+	m.Observe(SearchStats{Expanded: 1e6}, time.Second, ErrInvalidPoint) // не попадает в гистограммы
+
+	var buf bytes.Buffer
+	if err := m.WriteOpenMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != metricsGolden {
+		t.Errorf("got:\n%s\nwant:\n%s", got, metricsGolden)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Errorf("content type %q", ct)
+	}
+	if rec.Body.String() != metricsGolden {
+		t.Error("ServeHTTP output differs from WriteOpenMetrics")
+	}
+}
+
+func TestSearchMetricsSearch(t *testing.T) {
+	m := NewSearchMetrics()
+	s := NewSearcher(NewGrid(4, 4))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Search(context.Background(), s, Point{0, 0}, Point{3, 3})
+	m.Search(ctx, s, Point{0, 0}, Point{3, 3})
+
+	var buf bytes.Buffer
+	m.WriteOpenMetrics(&buf)
+	for _, want := range []string{
+		`astar_queries_total{outcome="found"} 1`,
+		`astar_queries_total{outcome="cancelled"} 1`,
+		"astar_searches_in_flight 0",
+		"astar_search_duration_seconds_count 2",
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MovingAITerrain сопоставляет символы карт MovingAI стоимости клетки,
+// +Inf - препятствие. Болото 'S' по правилам бенчмарка проходимо наравне
+// с обычной клеткой, а вода 'W' проходима только из воды, поэтому
+// для поиска по суше считается препятствием.
+var MovingAITerrain = map[byte]float64{
+	'.': 1,
+	'G': 1,
+	'S': 1,
+	'@': math.Inf(1),
+	'O': math.Inf(1),
+	'T': math.Inf(1),
+	'W': math.Inf(1),
+}
+
+// MovingAIScenario - одна задача из .scen файла
+type MovingAIScenario struct {
+	Bucket        int
+	Map           string
+	Width, Height int
+	Start, Goal   Point
+	Optimal       float64
+}
+
+// ReadMovingAIMap читает карту в формате MovingAI (.map)
+func ReadMovingAIMap(r io.Reader) (*Grid, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<24)
+
+	width, height := -1, -1
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "map" {
+			break
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: malformed header %q", line, scanner.Text())
+		}
+		switch fields[0] {
+		case "type":
+			if fields[1] != "octile" {
+				return nil, fmt.Errorf("line %d: unsupported map type %q", line, fields[1])
+			}
+		case "width", "height":
+			v, err := strconv.Atoi(fields[1])
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, fields[0], fields[1])
+			}
+			if fields[0] == "width" {
+				width = v
+			} else {
+				height = v
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown header %q", line, fields[0])
+		}
+	}
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("map header must declare width and height")
+	}
+	if err := checkGridSize(width, height); err != nil {
+		return nil, err
+	}
+
+	grid := NewGrid(width, height)
+	for y := 0; y < height; y++ {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("map has %d rows, header declares %d", y, height)
+		}
+		line++
+		row := strings.TrimRight(scanner.Text(), "\r")
+		if len(row) != width {
+			return nil, fmt.Errorf("line %d: row has %d cells, header declares %d", line, len(row), width)
+		}
+		for x := 0; x < width; x++ {
+			cost, ok := MovingAITerrain[row[x]]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown terrain %q at column %d", line, row[x], x)
+			}
+			if math.IsInf(cost, 1) {
+				grid.AddObstacle(Point{x, y})
+			} else {
+				grid.SetCost(Point{x, y}, cost)
+			}
+		}
+	}
+	return grid, scanner.Err()
+}
+
+// LoadMovingAIMap читает .map файл с диска
+func LoadMovingAIMap(path string) (*Grid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	grid, err := ReadMovingAIMap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return grid, nil
+}
+
+// ReadMovingAIScenarios читает задачи в формате MovingAI (.scen, version 1)
+func ReadMovingAIScenarios(r io.Reader) ([]MovingAIScenario, error) {
+	scanner := bufio.NewScanner(r)
+	var scenarios []MovingAIScenario
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if line == 1 && strings.HasPrefix(text, "version") {
+			if text != "version 1" && text != "version 1.0" {
+				return nil, fmt.Errorf("line 1: unsupported scenario %q", text)
+			}
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("line %d: expected 9 fields, got %d", line, len(fields))
+		}
+
+		var ints [7]int
+		for i, j := range [7]int{0, 2, 3, 4, 5, 6, 7} {
+			v, err := strconv.Atoi(fields[j])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: field %d: %v", line, j+1, err)
+			}
+			ints[i] = v
+		}
+		optimal, err := strconv.ParseFloat(fields[8], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: optimal length: %v", line, err)
+		}
+
+		scenarios = append(scenarios, MovingAIScenario{
+			Bucket:  ints[0],
+			Map:     fields[1],
+			Width:   ints[1],
+			Height:  ints[2],
+			Start:   Point{ints[3], ints[4]},
+			Goal:    Point{ints[5], ints[6]},
+			Optimal: optimal,
+		})
+	}
+	return scenarios, scanner.Err()
+}
+
+// LoadMovingAIScenarios читает .scen файл с диска
+func LoadMovingAIScenarios(path string) ([]MovingAIScenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scenarios, err := ReadMovingAIScenarios(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return scenarios, nil
+}
+
+// movingAIBucket - агрегированные результаты одной корзины сценариев
+type movingAIBucket struct {
+	count, failed  int
+	elapsed        time.Duration
+	expanded       int
+	maxExpanded    int
+	maxLengthError float64
+}
+
+// RunMovingAI решает сценарии на карте 8-связным A* с октильной эвристикой,
+// сверяет длину пути с эталонной и печатает таблицу по корзинам.
+// Возвращает ошибку, если хотя бы один сценарий решен неверно.
+func RunMovingAI(w io.Writer, grid *Grid, scenarios []MovingAIScenario) error {
+	searcher := NewSearcher(grid)
+	searcher.SetMovement(Moves8)
+
+	buckets := make(map[int]*movingAIBucket)
+	failures := 0
+	for i, sc := range scenarios {
+		if sc.Width != grid.Width || sc.Height != grid.Height {
+			return fmt.Errorf("scenario %d: map %s is %dx%d, loaded grid is %dx%d",
+				i+1, sc.Map, sc.Width, sc.Height, grid.Width, grid.Height)
+		}
+
+		b := buckets[sc.Bucket]
+		if b == nil {
+			b = &movingAIBucket{}
+			buckets[sc.Bucket] = b
+		}
+		b.count++
+
+		begin := time.Now()
+		path, err := searcher.Search(sc.Start, sc.Goal)
+		b.elapsed += time.Since(begin)
+
+		stats := searcher.Stats()
+		b.expanded += stats.Expanded
+		b.maxExpanded = max(b.maxExpanded, stats.Expanded)
+
+		length := 0.0
+		if err == nil {
+			length = path[len(path)-1].GCost
+		}
+		diff := math.Abs(length - sc.Optimal)
+		b.maxLengthError = max(b.maxLengthError, diff)
+		if err != nil || diff > 1e-4*math.Max(1, sc.Optimal) {
+			b.failed++
+			failures++
+			fmt.Fprintf(w, "scenario %d (%d,%d)->(%d,%d): length %.8f, expected %.8f %v\n",
+				i+1, sc.Start.x, sc.Start.y, sc.Goal.x, sc.Goal.y, length, sc.Optimal, err)
+		}
+	}
+
+	ids := make([]int, 0, len(buckets))
+	for id := range buckets {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	fmt.Fprintf(w, "%6s %6s %6s %12s %12s %14s %12s %10s\n",
+		"bucket", "count", "failed", "avg ms", "total ms", "avg expanded", "max expanded", "max error")
+	for _, id := range ids {
+		b := buckets[id]
+		total := float64(b.elapsed.Microseconds()) / 1000
+		fmt.Fprintf(w, "%6d %6d %6d %12.3f %12.3f %14.1f %12d %10.2g\n",
+			id, b.count, b.failed, total/float64(b.count), total,
+			float64(b.expanded)/float64(b.count), b.maxExpanded, b.maxLengthError)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", failures, len(scenarios))
+	}
+	return nil
+}
+
+// RunMovingAIFile загружает сценарии и карту и запускает RunMovingAI.
+// Если mapPath пуст, карта ищется рядом с .scen файлом по имени из сценария.
+func RunMovingAIFile(w io.Writer, scenPath, mapPath string) error {
+	scenarios, err := LoadMovingAIScenarios(scenPath)
+	if err != nil {
+		return err
+	}
+	if len(scenarios) == 0 {
+		return fmt.Errorf("%s: no scenarios", scenPath)
+	}
+	if mapPath == "" {
+		mapPath = filepath.Join(filepath.Dir(scenPath), filepath.Base(scenarios[0].Map))
+	}
+
+	grid, err := LoadMovingAIMap(mapPath)
+	if err != nil {
+		return err
+	}
+	return RunMovingAI(w, grid, scenarios)
+}
+
+// WriteMovingAIMap записывает сетку в формате MovingAI: препятствия - '@',
+// проходимые клетки - '.'; стоимость рельефа в этом формате не сохраняется
+func WriteMovingAIMap(w io.Writer, grid *Grid) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "type octile\nheight %d\nwidth %d\nmap\n", grid.Height, grid.Width)
+	row := make([]byte, grid.Width+1)
+	row[grid.Width] = '\n'
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			row[x] = '.'
+			if grid.IsObstacle(Point{x, y}) {
+				row[x] = '@'
+			}
+		}
+		bw.Write(row)
+	}
+	return bw.Flush()
+}
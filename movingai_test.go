@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadMovingAIMap(t *testing.T) {
+	data := "type octile\n\nheight 3\nwidth 4\nmap\n" +
+		".GS@\r\n" +
+		"OTW.\r\n" +
+		"....\n"
+	grid, err := ReadMovingAIMap(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid.Width != 4 || grid.Height != 3 {
+		t.Fatalf("got %dx%d, want 4x3", grid.Width, grid.Height)
+	}
+	var obstacles []Point
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			p := Point{x, y}
+			if grid.IsObstacle(p) {
+				obstacles = append(obstacles, p)
+			} else if grid.Cost(p) != 1 {
+				t.Errorf("%v: cost %g, want 1", p, grid.Cost(p))
+			}
+		}
+	}
+	want := []Point{{3, 0}, {0, 1}, {1, 1}, {2, 1}}
+	if len(obstacles) != len(want) {
+		t.Fatalf("obstacles %v, want %v", obstacles, want)
+	}
+	for i := range want {
+		if obstacles[i] != want[i] {
+			t.Fatalf("obstacles %v, want %v", obstacles, want)
+		}
+	}
+}
+
+func TestReadMovingAIMapErrors(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"type", "type tile\nheight 1\nwidth 1\nmap\n.\n", `line 1: unsupported map type "tile"`},
+		{"malformed header", "type octile\nheight\n", `line 2: malformed header "height"`},
+		{"bad width", "type octile\nheight 1\nwidth -3\nmap\n.\n", `line 3: invalid width "-3"`},
+		{"unknown header", "type octile\ndepth 2\n", `line 2: unknown header "depth"`},
+		{"no width", "type octile\nheight 1\nmap\n.\n", "map header must declare width and height"},
+		{"too large", "type octile\nheight 100000\nwidth 100000\nmap\n", "grid 100000x100000 exceeds the limit"},
+		{"short row", "type octile\nheight 2\nwidth 3\nmap\n...\n..\n", "line 6: row has 2 cells, header declares 3"},
+		{"missing rows", "type octile\nheight 3\nwidth 1\nmap\n.\n", "map has 1 rows, header declares 3"},
+		{"terrain", "type octile\nheight 1\nwidth 3\nmap\n.x.\n", `line 5: unknown terrain 'x' at column 1`},
+	}
+	for _, tt := range tests {
+		_, err := ReadMovingAIMap(strings.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestReadMovingAIScenarios(t *testing.T) {
+	data := "version 1\n" +
+		"0\tmaps/a.map\t5\t5\t0\t0\t4\t4\t5.65685425\n" +
+		"\n" +
+		"3\tmaps/a.map\t5\t5\t1\t2\t3\t2\t2\n"
+	scenarios, err := ReadMovingAIScenarios(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MovingAIScenario{
+		{Bucket: 0, Map: "maps/a.map", Width: 5, Height: 5, Start: Point{0, 0}, Goal: Point{4, 4}, Optimal: 5.65685425},
+		{Bucket: 3, Map: "maps/a.map", Width: 5, Height: 5, Start: Point{1, 2}, Goal: Point{3, 2}, Optimal: 2},
+	}
+	if len(scenarios) != len(want) || scenarios[0] != want[0] || scenarios[1] != want[1] {
+		t.Errorf("got %+v, want %+v", scenarios, want)
+	}
+
+	for _, bad := range []struct{ data, want string }{
+		{"version 2\n", `line 1: unsupported scenario "version 2"`},
+		{"version 1\n0 a.map 5 5 0 0 4 4\n", "line 2: expected 9 fields, got 8"},
+		{"0 a.map 5 five 0 0 4 4 1\n", "line 1: field 4"},
+		{"0 a.map 5 5 0 0 4 4 long\n", "line 1: optimal length"},
+	} {
+		if _, err := ReadMovingAIScenarios(strings.NewReader(bad.data)); err == nil || !strings.Contains(err.Error(), bad.want) {
+			t.Errorf("%q: got %v, want an error containing %q", bad.data, err, bad.want)
+		}
+	}
+}
+
+// movingAITestMap - открытая карта 5x5 со стеной, отрезающей правый
+// нижний угол
+const movingAITestMap = "type octile\nheight 5\nwidth 5\nmap\n" +
+	".....\n" +
+	".....\n" +
+	".....\n" +
+	"...@@\n" +
+	"...@.\n"
+
+// TestRunMovingAI проверяет сверку длины пути с эталоном: расхождение
+// в пределах 1e-4 от длины (эталон записан с 8 знаками) проходит, больше -
+// нет, как и недостижимая цель
+func TestRunMovingAI(t *testing.T) {
+	grid, err := ReadMovingAIMap(strings.NewReader(movingAITestMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := func(bucket int, goal Point, optimal float64) MovingAIScenario {
+		return MovingAIScenario{Bucket: bucket, Map: "a.map", Width: 5, Height: 5, Goal: goal, Optimal: optimal}
+	}
+
+	var out bytes.Buffer
+	ok := []MovingAIScenario{
+		scenario(0, Point{4, 2}, 4.82842712),        // 2 диагонали и 2 шага
+		scenario(0, Point{2, 4}, 4.82842712+0.0004), // в пределах допуска
+		scenario(1, Point{4, 0}, 4),
+	}
+	if err := RunMovingAI(&out, grid, ok); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 3 {
+		t.Errorf("got table\n%s\nwant a header and 2 buckets", out.String())
+	}
+
+	out.Reset()
+	bad := append(ok,
+		scenario(1, Point{4, 0}, 4.001),      // за пределами допуска
+		scenario(2, Point{4, 4}, 5.65685425), // цель отрезана стеной
+	)
+	err = RunMovingAI(&out, grid, bad)
+	if err == nil || err.Error() != "2 of 5 scenarios failed" {
+		t.Errorf("got %v, want 2 of 5 scenarios failed", err)
+	}
+	for _, want := range []string{"scenario 4 (0,0)->(4,0)", "scenario 5 (0,0)->(4,4)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	wrongSize := []MovingAIScenario{{Map: "b.map", Width: 6, Height: 5, Goal: Point{1, 1}, Optimal: 1.41421356}}
+	if err := RunMovingAI(&out, grid, wrongSize); err == nil || !strings.Contains(err.Error(), "map b.map is 6x5") {
+		t.Errorf("scenario for another map: got %v", err)
+	}
+}
+
+// TestRunMovingAIFile проверяет поиск карты рядом со сценарием
+func TestRunMovingAIFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.map"), []byte(movingAITestMap), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scen := filepath.Join(dir, "a.map.scen")
+	if err := os.WriteFile(scen, []byte("version 1\n0\tmaps/a.map\t5\t5\t0\t0\t4\t2\t4.82842712\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := RunMovingAIFile(&out, scen, ""); err != nil {
+		t.Errorf("%v\n%s", err, out.String())
+	}
+	if err := RunMovingAIFile(&out, scen, filepath.Join(dir, "missing.map")); err == nil {
+		t.Error("missing map file: no error")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+// SearchObserver получает события поиска Searcher: для визуализации,
+// отладки, метрик и тестов. Методы вызываются синхронно внутри поиска,
+// поэтому должны быть быстрыми. Узлы принадлежат Searcher и действительны
+// только до следующего поиска. Без наблюдателя поиск не тратит на события
+// ничего, кроме проверки на nil.
+type SearchObserver interface {
+	// OnPush - узел добавлен в открытый список
+	OnPush(n *Node)
+	// OnPop - узел извлечен из открытого списка для раскрытия, включая
+	// цель. Stats и Trace в этот момент отражают текущее состояние поиска.
+	OnPop(n *Node)
+	// OnUpdate - стоимость узла в открытом списке уменьшена
+	OnUpdate(n *Node)
+	// OnClose - узел раскрыт и перенесен в закрытый список
+	OnClose(n *Node)
+	// OnGoal - цель достигнута, path - найденный путь
+	OnGoal(path []*Node)
+}
+
+// ObserverFuncs - SearchObserver из отдельных функций; nil-поля
+// пропускаются. Удобен, когда нужны одно-два события.
+type ObserverFuncs struct {
+	Push, Pop, Update, Close func(n *Node)
+	Goal                     func(path []*Node)
+}
+
+func (o ObserverFuncs) OnPush(n *Node) {
+	if o.Push != nil {
+		o.Push(n)
+	}
+}
+
+func (o ObserverFuncs) OnPop(n *Node) {
+	if o.Pop != nil {
+		o.Pop(n)
+	}
+}
+
+func (o ObserverFuncs) OnUpdate(n *Node) {
+	if o.Update != nil {
+		o.Update(n)
+	}
+}
+
+func (o ObserverFuncs) OnClose(n *Node) {
+	if o.Close != nil {
+		o.Close(n)
+	}
+}
+
+func (o ObserverFuncs) OnGoal(path []*Node) {
+	if o.Goal != nil {
+		o.Goal(path)
+	}
+}
+
+// MultiObserver рассылает события всем наблюдателям по порядку;
+// nil-наблюдатели пропускаются
+func MultiObserver(observers ...SearchObserver) SearchObserver {
+	var list multiObserver
+	for _, o := range observers {
+		if o != nil {
+			list = append(list, o)
+		}
+	}
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return list
+}
+
+type multiObserver []SearchObserver
+
+func (m multiObserver) OnPush(n *Node) {
+	for _, o := range m {
+		o.OnPush(n)
+	}
+}
+
+func (m multiObserver) OnPop(n *Node) {
+	for _, o := range m {
+		o.OnPop(n)
+	}
+}
+
+func (m multiObserver) OnUpdate(n *Node) {
+	for _, o := range m {
+		o.OnUpdate(n)
+	}
+}
+
+func (m multiObserver) OnClose(n *Node) {
+	for _, o := range m {
+		o.OnClose(n)
+	}
+}
+
+func (m multiObserver) OnGoal(path []*Node) {
+	for _, o := range m {
+		o.OnGoal(path)
+	}
+}
+
+// SearchEventKind - вид события поиска
+type SearchEventKind uint8
+
+const (
+	EventPush SearchEventKind = iota
+	EventPop
+	EventUpdate
+	EventClose
+	EventGoal
+)
+
+var searchEventNames = [...]string{
+	EventPush:   "push",
+	EventPop:    "pop",
+	EventUpdate: "update",
+	EventClose:  "close",
+	EventGoal:   "goal",
+}
+
+func (k SearchEventKind) String() string {
+	if int(k) >= len(searchEventNames) {
+		return "unknown"
+	}
+	return searchEventNames[k]
+}
+
+// SearchEvent - запись события поиска: клетка и стоимости узла
+// в момент события. Для EventGoal клетка - цель.
+type SearchEvent struct {
+	Kind     SearchEventKind
+	Position Point
+	G, H, F  float64
+}
+
+// SearchRecorder - наблюдатель, записывающий все события поиска
+// по порядку. Reset очищает запись перед следующим поиском.
+type SearchRecorder struct {
+	Events []SearchEvent
+}
+
+func (r *SearchRecorder) add(kind SearchEventKind, n *Node) {
+	r.Events = append(r.Events, SearchEvent{kind, n.Position, n.GCost, n.HCost, n.FCost})
+}
+
+func (r *SearchRecorder) OnPush(n *Node)   { r.add(EventPush, n) }
+func (r *SearchRecorder) OnPop(n *Node)    { r.add(EventPop, n) }
+func (r *SearchRecorder) OnUpdate(n *Node) { r.add(EventUpdate, n) }
+func (r *SearchRecorder) OnClose(n *Node)  { r.add(EventClose, n) }
+
+func (r *SearchRecorder) OnGoal(path []*Node) {
+	r.add(EventGoal, path[len(path)-1])
+}
+
+// Reset очищает запись, сохраняя выделенную память
+func (r *SearchRecorder) Reset() {
+	r.Events = r.Events[:0]
+}
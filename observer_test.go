@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSearchRecorderOrder записывает поиск на сетке 2x2 с дорогой целью:
+// цель сначала добавляется по диагонали за 3*√2, затем дешевеет до 4
+// через соседа по стороне - это единственное уменьшение ключа
+func TestSearchRecorderOrder(t *testing.T) {
+	grid := NewGrid(2, 2)
+	grid.SetCost(Point{1, 1}, 3)
+	s := NewSearcher(grid)
+	s.SetMovement(Moves8)
+	s.SetAlgorithm(AlgorithmDijkstra, 1)
+	var r SearchRecorder
+	s.SetObserver(&r)
+
+	path, err := s.Search(Point{0, 0}, Point{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []SearchEventKind
+	for _, e := range r.Events {
+		kinds = append(kinds, e.Kind)
+	}
+	want := []SearchEventKind{
+		EventPush,            // старт
+		EventPop, EventClose, // старт раскрыт
+		EventPush, EventPush, EventPush, // три соседа
+		EventPop, EventClose, EventUpdate, // первый сосед по стороне удешевляет цель
+		EventPop, EventClose, // второй сосед по стороне
+		EventPop, EventGoal, // цель
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("events %v, want %v", kinds, want)
+	}
+
+	var diagonal, update, goalPop, goal SearchEvent
+	for i, e := range r.Events {
+		switch {
+		case e.Kind == EventPush && e.Position == Point{1, 1}:
+			diagonal = e
+		case e.Kind == EventUpdate:
+			update = e
+		case e.Kind == EventPop && i == len(r.Events)-2:
+			goalPop = e
+		case e.Kind == EventGoal:
+			goal = e
+		}
+	}
+	if step, _ := grid.StepCost(Point{0, 0}, Point{1, 1}); diagonal.G != step {
+		t.Errorf("goal pushed with g %g, want %g", diagonal.G, step)
+	}
+	if update.Position != (Point{1, 1}) || update.G != 4 || update.F != 4 {
+		t.Errorf("update %+v, want the goal at g 4", update)
+	}
+	if goalPop.Position != (Point{1, 1}) || goalPop.G != 4 || goal.Position != (Point{1, 1}) || goal.G != 4 {
+		t.Errorf("goal popped as %+v and reported as %+v, want g 4", goalPop, goal)
+	}
+	if path[len(path)-1].GCost != 4 {
+		t.Errorf("path cost %g, want 4", path[len(path)-1].GCost)
+	}
+
+	stats := s.Stats()
+	counts := map[SearchEventKind]int{}
+	for _, e := range r.Events {
+		counts[e.Kind]++
+	}
+	if counts[EventPush] != stats.Pushed || counts[EventPop] != stats.Expanded || counts[EventUpdate] != stats.Updated {
+		t.Errorf("event counts %v disagree with stats %+v", counts, stats)
+	}
+
+	r.Reset()
+	if len(r.Events) != 0 {
+		t.Error("Reset kept events")
+	}
+}
+
+func TestMultiObserver(t *testing.T) {
+	if MultiObserver() != nil || MultiObserver(nil, nil) != nil {
+		t.Error("observer list without observers isn't nil")
+	}
+	var a SearchRecorder
+	if o := MultiObserver(nil, &a); o != SearchObserver(&a) {
+		t.Errorf("single observer wrapped: %T", o)
+	}
+
+	var b SearchRecorder
+	goals := 0
+	s := NewSearcher(NewGrid(5, 5))
+	s.SetObserver(MultiObserver(&a, nil, &b, ObserverFuncs{Goal: func([]*Node) { goals++ }}))
+	if _, err := s.Search(Point{0, 0}, Point{4, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Events) == 0 || !reflect.DeepEqual(a.Events, b.Events) || goals != 1 {
+		t.Errorf("observers saw different searches: %d and %d events, %d goals", len(a.Events), len(b.Events), goals)
+	}
+}
+
+// TestNilObserverAllocs проверяет, что поиск без наблюдателя на
+// переиспользуемом Searcher не выделяет память
+func TestNilObserverAllocs(t *testing.T) {
+	s := NewSearcher(NewGrid(30, 30))
+	s.SetMovement(Moves8)
+	s.Search(Point{0, 0}, Point{29, 29})
+	allocs := testing.AllocsPerRun(20, func() {
+		s.Search(Point{0, 0}, Point{29, 29})
+	})
+	if allocs != 0 {
+		t.Errorf("search without an observer: %g allocations, want 0", allocs)
+	}
+}
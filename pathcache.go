@@ -0,0 +1,251 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+// PathCacheStats - счетчики кэша путей
+type PathCacheStats struct {
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	Invalidated uint64  `json:"invalidated"` // записей удалено из-за правок сетки
+	Evicted     uint64  `json:"evicted"`     // записей вытеснено по размеру
+	Entries     int     `json:"entries"`
+	GridVersion uint64  `json:"grid_version"`
+	HitRate     float64 `json:"hit_rate"` // доля попаданий; 0 - запросов не было
+}
+
+// pathKey - запрос к кэшу; настройки поиска задает Searcher кэша
+type pathKey struct {
+	start, goal Point
+}
+
+type pathEntry struct {
+	key   pathKey
+	path  []*Node
+	cells []int // клетки, от которых зависит путь: сам путь и углы диагоналей
+}
+
+// PathCache - LRU-кэш найденных путей перед Searcher. Ключ - концы пути
+// и версия сетки: кэш помнит версию, с которой согласованы записи.
+// Кэш подписан на правки сетки и удаляет только затронутые записи:
+//   - препятствие или новая стоимость на клетке пути (или в углу его
+//     диагонального шага) делают путь недействительным;
+//   - убранное препятствие или уменьшенная стоимость могут дать путь
+//     короче - удаляются записи, для которых нижняя оценка пути через
+//     клетку меньше их стоимости. При движении с диагоналями освобожденный
+//     угол открывает и диагональ между соседями клетки, поэтому оценка
+//     проверяется и для ее соседей по стороне.
+//
+// Прямые записи в Obstacles и Costs кэш не видит - после них нужен Clear.
+// Отсутствие пути не кэшируется. Настройки Searcher после создания кэша
+// менять нельзя - иначе нужен Clear. Кэш безопасен для одновременного
+// использования, но поиски выполняются по одному, а правки сетки не должны
+// идти одновременно с поиском.
+type PathCache struct {
+	mu          sync.Mutex
+	unsubscribe func()
+	searcher    *Searcher
+	grid        *Grid
+	capacity    int
+	version     uint64
+	lru         *list.List // *pathEntry, недавние - в начале
+	entries     map[pathKey]*list.Element
+	byCell      map[int]map[*pathEntry]struct{}
+	stats       PathCacheStats
+}
+
+// NewPathCache создает кэш не больше capacity путей для поисков searcher
+// по сетке Grid
+func NewPathCache(searcher *Searcher, capacity int) *PathCache {
+	if searcher.grid == nil {
+		panic("astar: path cache needs a Grid searcher")
+	}
+	c := &PathCache{
+		searcher: searcher,
+		grid:     searcher.grid,
+		capacity: max(1, capacity),
+	}
+	c.clear()
+	c.unsubscribe = c.grid.Subscribe(c.gridChanged)
+	return c
+}
+
+// Close отписывает кэш от правок сетки
+func (c *PathCache) Close() {
+	c.unsubscribe()
+}
+
+func (c *PathCache) clear() {
+	c.lru = list.New()
+	c.entries = make(map[pathKey]*list.Element)
+	c.byCell = make(map[int]map[*pathEntry]struct{})
+	c.version = c.grid.Version()
+}
+
+// Clear удаляет все записи
+func (c *PathCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+}
+
+// Search возвращает путь из кэша или ищет его и запоминает. Узлы пути
+// принадлежат кэшу и не должны изменяться; в отличие от Searcher.Search
+// они остаются действительными и после следующих вызовов.
+func (c *PathCache) Search(start, goal Point) ([]*Node, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := pathKey{start, goal}
+	if el, ok := c.entries[key]; ok {
+		c.stats.Hits++
+		c.lru.MoveToFront(el)
+		return el.Value.(*pathEntry).path, nil
+	}
+	c.stats.Misses++
+
+	found, err := c.searcher.Search(start, goal)
+	if err != nil {
+		return nil, err
+	}
+	e := &pathEntry{key: key, path: copyPath(found), cells: c.dependentCells(found)}
+	c.entries[key] = c.lru.PushFront(e)
+	for _, cell := range e.cells {
+		set := c.byCell[cell]
+		if set == nil {
+			set = make(map[*pathEntry]struct{})
+			c.byCell[cell] = set
+		}
+		set[e] = struct{}{}
+	}
+	if c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back().Value.(*pathEntry))
+		c.stats.Evicted++
+	}
+	return e.path, nil
+}
+
+// copyPath копирует путь из пула Searcher в собственные узлы
+func copyPath(path []*Node) []*Node {
+	nodes := make([]Node, len(path))
+	out := make([]*Node, len(path))
+	for i, n := range path {
+		nodes[i] = Node{Position: n.Position, GCost: n.GCost, HCost: n.HCost, FCost: n.FCost, Index: -1}
+		if i > 0 {
+			nodes[i].Parent = &nodes[i-1]
+		}
+		out[i] = &nodes[i]
+	}
+	return out
+}
+
+// dependentCells перечисляет клетки пути и клетки, которые диагональные
+// шаги пути огибают: препятствие на них тоже запрещает шаг
+func (c *PathCache) dependentCells(path []*Node) []int {
+	cells := make([]int, 0, len(path))
+	for i, n := range path {
+		cells = append(cells, c.grid.index(n.Position))
+		if i == 0 {
+			continue
+		}
+		prev := path[i-1].Position
+		if prev.x != n.Position.x && prev.y != n.Position.y {
+			cells = append(cells,
+				c.grid.index(Point{n.Position.x, prev.y}),
+				c.grid.index(Point{prev.x, n.Position.y}))
+		}
+	}
+	return cells
+}
+
+func (c *PathCache) remove(e *pathEntry) {
+	el, ok := c.entries[e.key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(el)
+	delete(c.entries, e.key)
+	for _, cell := range e.cells {
+		if set := c.byCell[cell]; set != nil {
+			delete(set, e)
+			if len(set) == 0 {
+				delete(c.byCell, cell)
+			}
+		}
+	}
+}
+
+// invalidateCell удаляет записи, проходящие через клетку p
+func (c *PathCache) invalidateCell(p Point) {
+	for e := range c.byCell[c.grid.index(p)] {
+		c.remove(e)
+		c.stats.Invalidated++
+	}
+}
+
+// invalidateShortcuts удаляет записи, которые клетки points, ставшие
+// дешевле или проходимыми, могут сократить: нижняя оценка пути через
+// одну из них меньше стоимости записи. Оценка верна, потому что Grid не
+// принимает стоимость клетки меньше 1.
+func (c *PathCache) invalidateShortcuts(points []Point) {
+	bound := c.searcher.movement.DefaultHeuristic()
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*pathEntry)
+		cost := e.path[len(e.path)-1].GCost
+		for _, p := range points {
+			if bound(e.key.start, p)+bound(p, e.key.goal) < cost {
+				c.remove(e)
+				c.stats.Invalidated++
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// gridChanged удаляет записи, которые правка сетки могла испортить:
+// пути через измененные клетки и пути, которые могут стать короче через
+// освободившиеся или подешевевшие клетки
+func (c *PathCache) gridChanged(change GridChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var shortcuts []Point
+	for _, cell := range change.Cells {
+		c.invalidateCell(cell.Point)
+		if cell.Obstacle || !(cell.WasObstacle || cell.Cost < cell.OldCost) {
+			continue
+		}
+		shortcuts = append(shortcuts, cell.Point)
+		if cell.WasObstacle && c.searcher.movement == Moves8 {
+			// Освобожденный угол открывает диагональ между соседями
+			// клетки, которая через нее саму не проходит: такой путь
+			// проходит через ее соседей по стороне
+			p := cell.Point
+			for _, n := range [...]Point{{p.x - 1, p.y}, {p.x + 1, p.y}, {p.x, p.y - 1}, {p.x, p.y + 1}} {
+				if c.grid.IsValid(n) {
+					shortcuts = append(shortcuts, n)
+				}
+			}
+		}
+	}
+	if len(shortcuts) > 0 {
+		c.invalidateShortcuts(shortcuts)
+	}
+	c.version = change.Version
+}
+
+// Stats возвращает счетчики кэша
+func (c *PathCache) Stats() PathCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.GridVersion = c.version
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
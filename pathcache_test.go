@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestPathCacheDiagonalCorner - освобожденный угол открывает диагональ,
+// которая через него не проходит, и кэш должен забыть более длинный путь
+func TestPathCacheDiagonalCorner(t *testing.T) {
+	grid := NewGrid(3, 3)
+	grid.AddObstacle(Point{1, 0})
+	searcher := NewSearcher(grid)
+	searcher.SetMovement(Moves8)
+	cache := NewPathCache(searcher, 16)
+	defer cache.Close()
+
+	path, err := cache.Search(Point{0, 0}, Point{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost := path[len(path)-1].GCost; cost != 2 {
+		t.Fatalf("cost around the corner: got %g, want 2", cost)
+	}
+	grid.RemoveObstacle(Point{1, 0})
+	path, err = cache.Search(Point{0, 0}, Point{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost := path[len(path)-1].GCost; math.Abs(cost-math.Sqrt2) > 1e-9 {
+		t.Errorf("cost after the corner is freed: got %g, want %g", cost, math.Sqrt2)
+	}
+}
+
+// TestPathCacheMatchesSearch сравнивает пути из кэша с новым поиском
+// после случайных правок сетки, в том числе стоимостей меньше 1
+func TestPathCacheMatchesSearch(t *testing.T) {
+	// Стоимость меньше 1 сломала бы нижнюю оценку кэша, поэтому сетка ее
+	// не принимает: дешевый ряд 0 не дает пути стоимостью 1.5
+	grid := NewGrid(5, 3)
+	cache := NewPathCache(NewSearcher(grid), 4)
+	if _, err := cache.Search(Point{0, 1}, Point{4, 1}); err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 5; x++ {
+		grid.SetCost(Point{x, 0}, 0.1)
+	}
+	want, _ := NewSearcher(grid).Search(Point{0, 1}, Point{4, 1})
+	got, _ := cache.Search(Point{0, 1}, Point{4, 1})
+	if a, b := got[len(got)-1].GCost, want[len(want)-1].GCost; a != b {
+		t.Errorf("cheap row: cache cost %g, search cost %g", a, b)
+	}
+	cache.Close()
+
+	costs := []float64{0.1, 0.5, 1, 2, 3, 4}
+	for _, movement := range []Movement{Moves4, Moves8} {
+		rng := rand.New(rand.NewSource(1))
+		grid := NewGrid(12, 12)
+		for i := 0; i < 40; i++ {
+			grid.AddObstacle(Point{rng.Intn(12), rng.Intn(12)})
+		}
+		cached := NewSearcher(grid)
+		cached.SetMovement(movement)
+		cache := NewPathCache(cached, 64)
+		fresh := NewSearcher(grid)
+		fresh.SetMovement(movement)
+
+		var queries [][2]Point
+		for i := 0; i < 20; i++ {
+			queries = append(queries, [2]Point{{rng.Intn(12), rng.Intn(12)}, {rng.Intn(12), rng.Intn(12)}})
+		}
+		for step := 0; step < 200; step++ {
+			p := Point{rng.Intn(12), rng.Intn(12)}
+			switch rng.Intn(3) {
+			case 0:
+				grid.AddObstacle(p)
+			case 1:
+				grid.RemoveObstacle(p)
+			default:
+				grid.SetCost(p, costs[rng.Intn(len(costs))])
+			}
+			for _, q := range queries {
+				want, wantErr := fresh.Search(q[0], q[1])
+				got, err := cache.Search(q[0], q[1])
+				if (wantErr == nil) != (err == nil) {
+					t.Fatalf("movement %v, step %d, %v: cache error %v, search error %v", movement, step, q, err, wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				if a, b := got[len(got)-1].GCost, want[len(want)-1].GCost; math.Abs(a-b) > 1e-9 {
+					t.Fatalf("movement %v, step %d, %v: cache cost %g, search cost %g", movement, step, q, a, b)
+				}
+			}
+		}
+		cache.Close()
+	}
+}
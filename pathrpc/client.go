@@ -0,0 +1,71 @@
+package pathrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Client - клиент сервиса поиска пути. Все вызовы идут с кодеком JSON.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient создает клиента поверх соединения, например grpc.NewClient
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+func callOptions(opts []grpc.CallOption) []grpc.CallOption {
+	return append([]grpc.CallOption{grpc.CallContentSubtype(CodecName)}, opts...)
+}
+
+// FindPath ищет путь
+func (c *Client) FindPath(ctx context.Context, in *FindPathRequest, opts ...grpc.CallOption) (*PathResponse, error) {
+	out := new(PathResponse)
+	err := c.cc.Invoke(ctx, "/"+ServiceName+"/FindPath", in, out, callOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateGrid правит сетку и возвращает ее новое описание
+func (c *Client) UpdateGrid(ctx context.Context, in *UpdateGridRequest, opts ...grpc.CallOption) (*GridInfo, error) {
+	out := new(GridInfo)
+	err := c.cc.Invoke(ctx, "/"+ServiceName+"/UpdateGrid", in, out, callOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StreamSearch запускает поиск с событиями раскрытия. Отмена ctx
+// прерывает поиск на сервере.
+func (c *Client) StreamSearch(ctx context.Context, in *StreamSearchRequest, opts ...grpc.CallOption) (*SearchStream, error) {
+	stream, err := c.cc.NewStream(ctx, &ServiceDesc.Streams[0], "/"+ServiceName+"/StreamSearch", callOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &SearchStream{stream}, nil
+}
+
+// SearchStream - поток событий StreamSearch на стороне клиента
+type SearchStream struct {
+	grpc.ClientStream
+}
+
+// Recv возвращает следующее событие; io.EOF - поток завершен
+func (s *SearchStream) Recv() (*SearchEvent, error) {
+	ev := new(SearchEvent)
+	if err := s.ClientStream.RecvMsg(ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
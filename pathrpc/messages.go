@@ -0,0 +1,118 @@
+// Package pathrpc описывает gRPC-сервис поиска пути: сообщения, описание
+// сервиса и клиент. Сообщения передаются в JSON через кодек, который
+// регистрируется при импорте пакета, поэтому protoc не нужен. Кодек
+// зарегистрирован под собственным именем CodecName, а не под общим
+// "json": реестр кодеков gRPC общий на весь процесс, и пакет не должен
+// подменять кодек "json" других библиотек. Файла .proto у сервиса нет, и
+// клиенты на protobuf с ним несовместимы: вызывать его можно только из Go
+// через этот пакет или клиентом, который отправляет JSON с подтипом
+// содержимого CodecName (application/grpc+astar-json). Сервер
+// реализован в основной программе (astar serve -grpc).
+package pathrpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Point - клетка сетки как пара x, y
+type Point = [2]int
+
+// FindPathRequest - запрос поиска пути. Пустые поля берут значения
+// по умолчанию: концы из загруженной карты, astar, движение 4,
+// эвристика по модели движения, время поиска из настроек сервера.
+type FindPathRequest struct {
+	Grid      string  `json:"grid"`
+	Start     *Point  `json:"start,omitempty"`
+	Goal      *Point  `json:"goal,omitempty"`
+	Algorithm string  `json:"algorithm,omitempty"`
+	Weight    float64 `json:"weight,omitempty"`
+	Heuristic string  `json:"heuristic,omitempty"`
+	Movement  int     `json:"movement,omitempty"`
+	TimeoutMS int     `json:"timeout_ms,omitempty"`
+}
+
+// StreamSearchRequest - запрос поиска с событиями раскрытия
+type StreamSearchRequest struct {
+	FindPathRequest
+	// Every - событие через каждые Every раскрытий; 0 - на каждое
+	Every int `json:"every,omitempty"`
+}
+
+// SearchStats - счетчики поиска
+type SearchStats struct {
+	Expanded int `json:"expanded"`
+	Pushed   int `json:"pushed"`
+	Updated  int `json:"updated"`
+	MaxOpen  int `json:"max_open"`
+}
+
+// PathResponse - результат поиска. Если пути нет, Found = false,
+// а Error объясняет причину.
+type PathResponse struct {
+	Found     bool        `json:"found"`
+	Error     string      `json:"error,omitempty"`
+	Start     Point       `json:"start"`
+	Goal      Point       `json:"goal"`
+	Steps     int         `json:"steps"`
+	Cost      float64     `json:"cost"`
+	Path      []Point     `json:"path"`
+	Stats     SearchStats `json:"stats"`
+	ElapsedMS float64     `json:"elapsed_ms"`
+}
+
+// SearchEvent - раскрытие узла во время поиска. Последнее событие потока
+// несет итог поиска в Result. Сервер не ждет медленного клиента: события,
+// которые не успели уйти, отбрасываются, их число - в Dropped последнего
+// события, а пропуски видны по Expanded.
+type SearchEvent struct {
+	Expanded int           `json:"expanded"` // номер раскрытия начиная с 1
+	Point    Point         `json:"point"`
+	G        float64       `json:"g"`
+	H        float64       `json:"h"`
+	F        float64       `json:"f"` // приоритет в открытом списке
+	Result   *PathResponse `json:"result,omitempty"`
+	Dropped  int           `json:"dropped,omitempty"` // отброшено событий
+}
+
+// CellCost - стоимость входа в клетку
+type CellCost struct {
+	Point Point   `json:"point"`
+	Cost  float64 `json:"cost"`
+}
+
+// UpdateGridRequest - правка сетки. Правки применяются целиком или не
+// применяются вовсе: сначала удаления, затем добавления, затем стоимости.
+type UpdateGridRequest struct {
+	Grid   string     `json:"grid"`
+	Add    []Point    `json:"add,omitempty"`
+	Remove []Point    `json:"remove,omitempty"`
+	Costs  []CellCost `json:"costs,omitempty"`
+}
+
+// GridInfo - описание сетки
+type GridInfo struct {
+	Name      string `json:"name"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Obstacles int    `json:"obstacles"`
+	Weighted  bool   `json:"weighted"`
+	Start     *Point `json:"start,omitempty"`
+	Goal      *Point `json:"goal,omitempty"`
+	Version   uint64 `json:"version"`
+}
+
+// CodecName - подтип содержимого gRPC для сообщений в JSON
+const CodecName = "astar-json"
+
+// jsonCodec кодирует сообщения пакета в JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return CodecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
@@ -0,0 +1,93 @@
+package pathrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// ServiceName - полное имя gRPC-сервиса
+const ServiceName = "astar.Pathfinder"
+
+// PathfinderServer - реализация сервиса на стороне сервера
+type PathfinderServer interface {
+	// FindPath ищет путь. Отсутствие пути - не ошибка: ответ с Found = false.
+	FindPath(context.Context, *FindPathRequest) (*PathResponse, error)
+	// StreamSearch ищет путь, отправляя события раскрытия узлов,
+	// и завершает поток событием с итогом поиска
+	StreamSearch(*StreamSearchRequest, StreamSearchServer) error
+	// UpdateGrid правит препятствия и стоимости сетки
+	UpdateGrid(context.Context, *UpdateGridRequest) (*GridInfo, error)
+}
+
+// StreamSearchServer - поток событий StreamSearch на стороне сервера
+type StreamSearchServer interface {
+	Send(*SearchEvent) error
+	grpc.ServerStream
+}
+
+// RegisterPathfinderServer регистрирует реализацию сервиса на сервере gRPC
+func RegisterPathfinderServer(s grpc.ServiceRegistrar, srv PathfinderServer) {
+	s.RegisterService(&ServiceDesc, srv)
+}
+
+// ServiceDesc - описание сервиса для grpc.Server, написанное вручную
+// вместо сгенерированного protoc
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*PathfinderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "FindPath", Handler: findPathHandler},
+		{MethodName: "UpdateGrid", Handler: updateGridHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "StreamSearch", Handler: streamSearchHandler, ServerStreams: true},
+	},
+	Metadata: "pathrpc",
+}
+
+func findPathHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(FindPathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PathfinderServer).FindPath(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/FindPath"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(PathfinderServer).FindPath(ctx, req.(*FindPathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func updateGridHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(UpdateGridRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PathfinderServer).UpdateGrid(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/UpdateGrid"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(PathfinderServer).UpdateGrid(ctx, req.(*UpdateGridRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func streamSearchHandler(srv any, stream grpc.ServerStream) error {
+	in := new(StreamSearchRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(PathfinderServer).StreamSearch(in, streamSearchServer{stream})
+}
+
+type streamSearchServer struct {
+	grpc.ServerStream
+}
+
+func (s streamSearchServer) Send(ev *SearchEvent) error {
+	return s.ServerStream.SendMsg(ev)
+}
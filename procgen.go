@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// endpoints возвращает старт и цель из параметров или значения по умолчанию
+func (o GenerateOptions) endpoints(start, goal Point) (Point, Point) {
+	if o.Start != nil {
+		start = *o.Start
+	}
+	if o.Goal != nil {
+		goal = *o.Goal
+	}
+	return start, goal
+}
+
+// finish проверяет концы и при необходимости соединяет их проходом
+func (o GenerateOptions) finish(grid *Grid, start, goal Point) (*MapFile, error) {
+	for _, p := range []Point{start, goal} {
+		if !grid.InBounds(p) {
+			return nil, fmt.Errorf("endpoint (%d,%d) is outside the %dx%d map", p.x, p.y, grid.Width, grid.Height)
+		}
+	}
+	if o.Connect {
+		EnsureConnected(grid, start, goal)
+	}
+	return &MapFile{Grid: grid, Start: &start, Goal: &goal}, nil
+}
+
+// GenerateCave строит пещеру клеточным автоматом: случайное заполнение
+// с плотностью Density, затем Iterations шагов правила "стена, если в
+// окрестности 3x3 не меньше 5 стен". Края карты всегда стены.
+func GenerateCave(opts GenerateOptions) (*MapFile, error) {
+	if opts.Density < 0 || opts.Density > 1 {
+		return nil, fmt.Errorf("density must be within [0, 1], got %g", opts.Density)
+	}
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = 5
+	}
+
+	w, h := opts.Width, opts.Height
+	rng := rand.New(rand.NewSource(opts.Seed))
+	border := func(x, y int) bool { return x == 0 || y == 0 || x == w-1 || y == h-1 }
+
+	cells := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cells[y*w+x] = border(x, y) || rng.Float64() < opts.Density
+		}
+	}
+
+	next := make([]bool, w*h)
+	for it := 0; it < iterations; it++ {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if border(x, y) {
+					next[y*w+x] = true
+					continue
+				}
+				walls := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if cells[(y+dy)*w+x+dx] {
+							walls++
+						}
+					}
+				}
+				next[y*w+x] = walls >= 5
+			}
+		}
+		cells, next = next, cells
+	}
+
+	grid := NewGrid(w, h)
+	copy(grid.Obstacles, cells)
+	start, goal := opts.endpoints(Point{1, 1}, Point{w - 2, h - 2})
+	return opts.finish(grid, start, goal)
+}
+
+// bspRect - прямоугольник клеток для разбиения подземелья
+type bspRect struct{ x, y, w, h int }
+
+func (r bspRect) center() Point {
+	return Point{r.x + r.w/2, r.y + r.h/2}
+}
+
+// GenerateDungeon строит подземелье двоичным разбиением пространства:
+// области делятся, пока не станут меньше двух комнат, в каждом листе
+// вырезается комната, а комнаты соседних поддеревьев соединяются
+// Г-образными коридорами. Старт и цель по умолчанию - центры первой и
+// последней комнаты.
+func GenerateDungeon(opts GenerateOptions) (*MapFile, error) {
+	minRoom := opts.RoomSize
+	if minRoom <= 0 {
+		minRoom = 5
+	}
+	w, h := opts.Width, opts.Height
+	if w < minRoom+2 || h < minRoom+2 {
+		return nil, fmt.Errorf("map %dx%d is too small for rooms of %d cells", w, h, minRoom)
+	}
+
+	grid := NewGrid(w, h)
+	for i := range grid.Obstacles {
+		grid.Obstacles[i] = true
+	}
+	carve := func(p Point) { grid.Obstacles[grid.index(p)] = false }
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	var rooms []bspRect
+	// split возвращает комнату поддерева, через которую его соединяют с соседним
+	var split func(area bspRect) bspRect
+	split = func(area bspRect) bspRect {
+		leaf := minRoom + 2 // комната плюс стена с каждой стороны
+		canV, canH := area.w >= 2*leaf, area.h >= 2*leaf
+		if canV || canH {
+			vertical := canV && (!canH || area.w > area.h || (area.w == area.h && rng.Intn(2) == 0))
+			var a, b bspRect
+			if vertical {
+				cut := leaf + rng.Intn(area.w-2*leaf+1)
+				a, b = bspRect{area.x, area.y, cut, area.h}, bspRect{area.x + cut, area.y, area.w - cut, area.h}
+			} else {
+				cut := leaf + rng.Intn(area.h-2*leaf+1)
+				a, b = bspRect{area.x, area.y, area.w, cut}, bspRect{area.x, area.y + cut, area.w, area.h - cut}
+			}
+			roomA, roomB := split(a), split(b)
+			// Г-образный коридор между центрами комнат
+			from, to := roomA.center(), roomB.center()
+			corner := Point{to.x, from.y}
+			if rng.Intn(2) == 0 {
+				corner = Point{from.x, to.y}
+			}
+			bresenham(from, corner, carve)
+			bresenham(corner, to, carve)
+			if rng.Intn(2) == 0 {
+				return roomA
+			}
+			return roomB
+		}
+
+		// Лист: комната случайного размера внутри области, не касаясь краев
+		rw := minRoom + rng.Intn(area.w-minRoom-1)
+		rh := minRoom + rng.Intn(area.h-minRoom-1)
+		room := bspRect{
+			x: area.x + 1 + rng.Intn(area.w-rw-1),
+			y: area.y + 1 + rng.Intn(area.h-rh-1),
+			w: rw,
+			h: rh,
+		}
+		for y := room.y; y < room.y+room.h; y++ {
+			for x := room.x; x < room.x+room.w; x++ {
+				carve(Point{x, y})
+			}
+		}
+		rooms = append(rooms, room)
+		return room
+	}
+	split(bspRect{0, 0, w, h})
+
+	start, goal := opts.endpoints(rooms[0].center(), rooms[len(rooms)-1].center())
+	return opts.finish(grid, start, goal)
+}
+
+// perlin - двумерный градиентный шум Перлина с перестановкой от зерна
+type perlin struct {
+	perm [512]uint8
+}
+
+func newPerlin(rng *rand.Rand) *perlin {
+	p := &perlin{}
+	for i, v := range rng.Perm(256) {
+		p.perm[i] = uint8(v)
+		p.perm[i+256] = uint8(v)
+	}
+	return p
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + t*(b-a)
+}
+
+func grad(hash uint8, x, y float64) float64 {
+	switch hash & 7 {
+	case 0:
+		return x + y
+	case 1:
+		return x - y
+	case 2:
+		return -x + y
+	case 3:
+		return -x - y
+	case 4:
+		return x
+	case 5:
+		return -x
+	case 6:
+		return y
+	default:
+		return -y
+	}
+}
+
+// noise возвращает значение шума в точке, примерно в диапазоне [-1, 1]
+func (p *perlin) noise(x, y float64) float64 {
+	xf, yf := math.Floor(x), math.Floor(y)
+	xi, yi := int(xf)&255, int(yf)&255
+	x, y = x-xf, y-yf
+	u, v := fade(x), fade(y)
+
+	aa := p.perm[int(p.perm[xi])+yi]
+	ab := p.perm[int(p.perm[xi])+yi+1]
+	ba := p.perm[int(p.perm[xi+1])+yi]
+	bb := p.perm[int(p.perm[xi+1])+yi+1]
+
+	return lerp(
+		lerp(grad(aa, x, y), grad(ba, x-1, y), u),
+		lerp(grad(ab, x, y-1), grad(bb, x-1, y-1), u),
+		v,
+	)
+}
+
+// fractal складывает четыре октавы шума с убывающей амплитудой
+func (p *perlin) fractal(x, y float64) float64 {
+	sum, amplitude, frequency, norm := 0.0, 1.0, 1.0, 0.0
+	for octave := 0; octave < 4; octave++ {
+		sum += amplitude * p.noise(x*frequency, y*frequency)
+		norm += amplitude
+		amplitude /= 2
+		frequency *= 2
+	}
+	return sum / norm
+}
+
+// GenerateTerrain строит рельеф по фрактальному шуму Перлина: стоимость
+// клеток растет от 1 во впадинах до MaxCost на вершинах, а доля Density
+// самых низких клеток становится непроходимой водой. Scale - характерный
+// размер форм рельефа в клетках.
+func GenerateTerrain(opts GenerateOptions) (*MapFile, error) {
+	if opts.Density < 0 || opts.Density > 1 {
+		return nil, fmt.Errorf("density must be within [0, 1], got %g", opts.Density)
+	}
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 16
+	}
+	maxCost := opts.MaxCost
+	if maxCost <= 0 {
+		maxCost = 5
+	}
+	if maxCost < 1 {
+		return nil, fmt.Errorf("max cost must be at least 1, got %g", maxCost)
+	}
+
+	w, h := opts.Width, opts.Height
+	noise := newPerlin(rand.New(rand.NewSource(opts.Seed)))
+	values := make([]float64, w*h)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := noise.fractal(float64(x)/scale, float64(y)/scale)
+			values[y*w+x] = v
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	water := math.Inf(-1)
+	if k := int(opts.Density * float64(len(sorted))); k > 0 {
+		water = sorted[k-1]
+	}
+
+	grid := NewGrid(w, h)
+	for i, v := range values {
+		p := Point{i % w, i / w}
+		if v <= water {
+			grid.AddObstacle(p)
+			continue
+		}
+		t := 0.0
+		if hi > lo {
+			t = (v - lo) / (hi - lo)
+		}
+		grid.SetCost(p, math.Round((1+t*(maxCost-1))*10)/10)
+	}
+
+	start, goal := opts.endpoints(Point{0, 0}, Point{w - 1, h - 1})
+	return opts.finish(grid, start, goal)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+var procGenerators = map[string]Generator{
+	"cave":    GenerateCave,
+	"dungeon": GenerateDungeon,
+	"terrain": GenerateTerrain,
+}
+
+// TestProcgenDeterministic проверяет, что одинаковое зерно дает ту же
+// карту с теми же концами, а разные зерна - разные карты
+func TestProcgenDeterministic(t *testing.T) {
+	for name, generate := range procGenerators {
+		opts := GenerateOptions{Width: 40, Height: 30, Seed: 7, Density: 0.4}
+		a, err := generate(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		b, err := generate(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !sameMapFile(a, b) {
+			t.Errorf("%s: same seed produced different maps", name)
+		}
+		opts.Seed = 8
+		c, err := generate(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if sameMapFile(a, c) {
+			t.Errorf("%s: seeds 7 and 8 produced the same map", name)
+		}
+	}
+}
+
+// TestProcgenConnect проверяет, что с Connect концы свободны и лежат в
+// одной области, даже когда препятствий много
+func TestProcgenConnect(t *testing.T) {
+	for name, generate := range procGenerators {
+		for _, density := range []float64{0.3, 0.6} {
+			for seed := int64(1); seed <= 5; seed++ {
+				label := fmt.Sprintf("%s/density%g/seed%d", name, density, seed)
+				m, err := generate(GenerateOptions{Width: 33, Height: 21, Seed: seed, Density: density, Connect: true})
+				if err != nil {
+					t.Errorf("%s: %v", label, err)
+					continue
+				}
+				start, goal := *m.Start, *m.Goal
+				labels, _ := Components(m.Grid)
+				if !m.Grid.IsValid(start) || !m.Grid.IsValid(goal) || labels[m.Grid.index(start)] != labels[m.Grid.index(goal)] {
+					t.Errorf("%s: (%d,%d) and (%d,%d) are not connected", label, start.x, start.y, goal.x, goal.y)
+					continue
+				}
+				if _, err := NewSearcher(m.Grid).Search(start, goal); err != nil {
+					t.Errorf("%s: %v", label, err)
+				}
+			}
+		}
+	}
+}
+
+func TestProcgenOptions(t *testing.T) {
+	start, goal := Point{2, 2}, Point{17, 12}
+	outside := Point{20, 0}
+	tests := []struct {
+		name     string
+		generate Generator
+		opts     GenerateOptions
+		wantErr  bool
+	}{
+		{"cave density", GenerateCave, GenerateOptions{Width: 20, Height: 15, Density: 1.5}, true},
+		{"terrain density", GenerateTerrain, GenerateOptions{Width: 20, Height: 15, Density: -0.1}, true},
+		{"terrain max cost", GenerateTerrain, GenerateOptions{Width: 20, Height: 15, MaxCost: 0.5}, true},
+		{"dungeon too small", GenerateDungeon, GenerateOptions{Width: 6, Height: 20}, true},
+		{"endpoint outside", GenerateCave, GenerateOptions{Width: 20, Height: 15, Goal: &outside}, true},
+		{"explicit endpoints", GenerateCave, GenerateOptions{Width: 20, Height: 15, Density: 0.5, Start: &start, Goal: &goal, Connect: true}, false},
+	}
+	for _, tt := range tests {
+		m, err := tt.generate(tt.opts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (*m.Start != start || *m.Goal != goal) {
+			t.Errorf("%s: endpoints %v and %v, want %v and %v", tt.name, *m.Start, *m.Goal, start, goal)
+		}
+	}
+}
+
+func TestEnsureConnected(t *testing.T) {
+	// Стена толщиной в две клетки через всю карту и концы на препятствиях
+	grid := NewGrid(7, 5)
+	grid.FillRect(Point{3, 0}, Point{4, 4}, true)
+	grid.AddObstacle(Point{0, 2})
+	grid.AddObstacle(Point{6, 2})
+	changes := recordChanges(grid)
+
+	if carved := EnsureConnected(grid, Point{0, 2}, Point{6, 2}); carved != 4 {
+		t.Errorf("carved %d cells, want 4: both ends and two wall cells", carved)
+	}
+	if len(*changes) != 1 || len((*changes)[0].Cells) != 4 {
+		t.Errorf("got notifications %+v, want one with 4 cells", *changes)
+	}
+	if _, err := NewSearcher(grid).Search(Point{0, 2}, Point{6, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	version := grid.Version()
+	if carved := EnsureConnected(grid, Point{0, 0}, Point{6, 4}); carved != 0 || grid.Version() != version {
+		t.Errorf("connected ends: carved %d cells, version %d, want nothing changed", carved, grid.Version())
+	}
+}
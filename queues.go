@@ -11,11 +11,11 @@ type Point struct {
 
 type Node struct {
 	Position Point
-	GCost float64 // g(n) - фактическое расстояние от старта до текущей позиции
-	HCost float64 // h(n) - эвристическая оценка от текущей позиции до цели
-	FCost float64 // f(n) = g(n) + h(n) - общая оценка качества пути через эту позицию
-	Parent *Node  // Указатель на родительский узел, чтобы востановить путь
-	Index int 	  // Индекс в куче для heap.Fix
+	GCost    float64 // g(n) - фактическое расстояние от старта до текущей позиции
+	HCost    float64 // h(n) - эвристическая оценка от текущей позиции до цели
+	FCost    float64 // f(n) = g(n) + h(n) - общая оценка качества пути через эту позицию
+	Parent   *Node   // Указатель на родительский узел, чтобы востановить путь
+	Index    int     // Индекс в куче для heap.Fix
 }
 
 func (n *Node) String() string {
@@ -24,10 +24,10 @@ func (n *Node) String() string {
 
 // implemention priority queue
 type OpenList []*Node
- 
+
 func (ol OpenList) Len() int {
 	return len(ol)
-} 
+}
 
 func (ol OpenList) Less(i, j int) bool {
 	return ol[i].FCost < ol[j].FCost
@@ -55,47 +55,95 @@ func (ol *OpenList) Pop() interface{} {
 	return node
 }
 
-func (ol *OpenList) Update(node *Node, g, h float64) {
-	node.GCost = g
-	node.HCost = h
-	node.FCost = g + h
-	heap.Fix(ol, node.Index) // Обновляем приоритет
+// PriorityQueue - открытый список поиска, упорядоченный по FCost.
+// Реализации используют Node.Index как собственный дескриптор узла.
+type PriorityQueue interface {
+	Len() int
+	Push(node *Node)
+	Pop() *Node
+	Update(node *Node) // FCost узла уменьшился
+	Reset()
 }
 
-func (ol *OpenList) Contains(point Point) *Node {
-	for _, node := range *ol {
-		if node.Position.x == point.x && node.Position.y == point.y {
-			return node
-		}
-	}
-	return nil
+// QueueKind выбирает реализацию PriorityQueue
+type QueueKind int
+
+const (
+	QueueBinary QueueKind = iota
+	QueueQuaternary
+	QueuePairing
+	QueueBucket
+	QueueRadix
+)
+
+var queueNames = [...]string{
+	QueueBinary:     "binary",
+	QueueQuaternary: "4-ary",
+	QueuePairing:    "pairing",
+	QueueBucket:     "bucket",
+	QueueRadix:      "radix",
 }
 
+func (k QueueKind) String() string {
+	if k < 0 || int(k) >= len(queueNames) {
+		return fmt.Sprintf("QueueKind(%d)", int(k))
+	}
+	return queueNames[k]
+}
 
-// ===================================================== // 
-// Реализация списка обработанных узлов // 
+// QueueKinds возвращает все доступные реализации очереди
+func QueueKinds() []QueueKind {
+	return []QueueKind{QueueBinary, QueueQuaternary, QueuePairing, QueueBucket, QueueRadix}
+}
 
-type ClosedList struct {
-	nodes map[string]*Node
+// ParseQueueKind находит реализацию очереди по имени
+func ParseQueueKind(name string) (QueueKind, error) {
+	for i, n := range queueNames {
+		if n == name {
+			return QueueKind(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown queue %q", name)
 }
 
-func NewClosedList() *ClosedList {
-	return &ClosedList{
-		nodes: make(map[string]*Node),
+// NewPriorityQueue создает очередь выбранного типа.
+// Очереди bucket и radix работают с целочисленными ключами FCost*1.
+func NewPriorityQueue(kind QueueKind) PriorityQueue {
+	switch kind {
+	case QueueQuaternary:
+		return NewDaryHeap(4)
+	case QueuePairing:
+		return &PairingHeap{root: -1}
+	case QueueBucket:
+		return NewBucketQueue(1)
+	case QueueRadix:
+		return NewRadixHeap(1)
+	default:
+		return &BinaryHeap{}
 	}
 }
 
-func (cl *ClosedList) Add(node *Node) {
-	key := fmt.Sprintf("%d,%d", node.Position.x, node.Position.y)
-	cl.nodes[key] = node
+// BinaryHeap - PriorityQueue поверх OpenList и container/heap
+type BinaryHeap struct {
+	list OpenList
 }
 
-func (cl *ClosedList) Contains(point Point) bool {
-	key := fmt.Sprintf("%d,%d", point.x, point.y)
-	_, exists := cl.nodes[key]
-	return exists
+func (h *BinaryHeap) Len() int {
+	return h.list.Len()
 }
 
+func (h *BinaryHeap) Push(node *Node) {
+	heap.Push(&h.list, node)
+}
 
+func (h *BinaryHeap) Pop() *Node {
+	return heap.Pop(&h.list).(*Node)
+}
 
+func (h *BinaryHeap) Update(node *Node) {
+	heap.Fix(&h.list, node.Index)
+}
 
+func (h *BinaryHeap) Reset() {
+	h.list = h.list[:0]
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomWeightedGrid строит сетку с препятствиями и целыми стоимостями
+// клеток от 1 до maxCost
+func randomWeightedGrid(rng *rand.Rand, width, height int, maxCost int) *Grid {
+	grid := NewGrid(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := Point{x, y}
+			if rng.Float64() < 0.2 {
+				grid.AddObstacle(p)
+			} else {
+				grid.SetCost(p, float64(1+rng.Intn(maxCost)))
+			}
+		}
+	}
+	return grid
+}
+
+// TestQueuesMatchBinaryHeap сравнивает стоимость путей всех очередей с
+// двоичной кучей на случайных взвешенных сетках. При движении по
+// сторонам с целыми стоимостями округление ключей bucket и radix точное,
+// поэтому стоимости совпадают, в том числе когда узлы дешевеют.
+func TestQueuesMatchBinaryHeap(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	updated := 0
+	for trial := 0; trial < 20; trial++ {
+		grid := randomWeightedGrid(rng, 30, 30, 9)
+		start, goal := Point{rng.Intn(30), rng.Intn(30)}, Point{rng.Intn(30), rng.Intn(30)}
+		grid.RemoveObstacle(start)
+		grid.RemoveObstacle(goal)
+		for _, alg := range []Algorithm{AlgorithmAStar, AlgorithmDijkstra} {
+			ref := NewSearcher(grid)
+			ref.SetAlgorithm(alg, 1)
+			want, wantErr := ref.Search(start, goal)
+			for _, kind := range QueueKinds() {
+				s := NewSearcher(grid)
+				s.SetAlgorithm(alg, 1)
+				s.SetQueue(NewPriorityQueue(kind))
+				got, err := s.Search(start, goal)
+				if (err == nil) != (wantErr == nil) {
+					t.Fatalf("trial %d, %v, %v: error %v, binary heap error %v", trial, alg, kind, err, wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				if a, b := got[len(got)-1].GCost, want[len(want)-1].GCost; a != b {
+					t.Errorf("trial %d, %v, %v: cost %g, binary heap cost %g", trial, alg, kind, a, b)
+				}
+				updated += s.Stats().Updated
+			}
+		}
+	}
+	if updated == 0 {
+		t.Error("no search decreased a node's cost: decrease-key is not covered")
+	}
+}
+
+// TestQueuesOrder проверяет порядок извлечения после уменьшения ключей,
+// включая ключи далеко за массивом корзин BucketQueue
+func TestQueuesOrder(t *testing.T) {
+	for _, kind := range QueueKinds() {
+		rng := rand.New(rand.NewSource(3))
+		q := NewPriorityQueue(kind)
+		nodes := make([]Node, 200)
+		for i := range nodes {
+			f := float64(rng.Intn(1000))
+			if i%10 == 0 {
+				f = math.Ldexp(float64(1+rng.Intn(1000)), 20+rng.Intn(990)) // до 1e300
+			}
+			nodes[i] = Node{Position: Point{i, 0}, FCost: f, Index: -1}
+			q.Push(&nodes[i])
+		}
+		for i := 0; i < len(nodes); i += 3 {
+			nodes[i].FCost = math.Floor(nodes[i].FCost / 2)
+			q.Update(&nodes[i])
+		}
+		want := make([]float64, len(nodes))
+		for i := range nodes {
+			want[i] = nodes[i].FCost
+		}
+		sort.Float64s(want)
+
+		for i, w := range want {
+			n := q.Pop()
+			if n == nil {
+				t.Fatalf("%v: queue empty after %d pops, want %d", kind, i, len(want))
+			}
+			if n.FCost != w {
+				t.Fatalf("%v: pop %d has f %g, want %g", kind, i, n.FCost, w)
+			}
+		}
+		if q.Len() != 0 {
+			t.Errorf("%v: queue length %d after all pops, want 0", kind, q.Len())
+		}
+	}
+}
+
+// TestQueuesHugeCosts - огромные конечные стоимости клеток не должны
+// ронять целочисленные очереди или раздувать их память
+func TestQueuesHugeCosts(t *testing.T) {
+	grid := NewGrid(20, 3)
+	for x := 0; x < 20; x++ {
+		grid.SetCost(Point{x, 1}, 1e300)
+		grid.SetCost(Point{x, 2}, 1e6+float64(x))
+	}
+	for _, kind := range QueueKinds() {
+		s := NewSearcher(grid)
+		q := NewPriorityQueue(kind)
+		s.SetQueue(q)
+		path, err := s.Search(Point{0, 2}, Point{19, 2})
+		if err != nil {
+			t.Fatalf("%v: %v", kind, err)
+		}
+		if len(path) != 20 {
+			t.Errorf("%v: path of %d cells, want 20 along the bottom row", kind, len(path))
+		}
+		if bq, ok := q.(*BucketQueue); ok && len(bq.buckets) > bucketQueueKeys {
+			t.Errorf("bucket queue grew to %d buckets", len(bq.buckets))
+		}
+	}
+}
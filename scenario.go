@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario - декларативное описание задачи: сетка, препятствия, рельеф,
+// параметры поиска, пары старт/цель и параметры отрисовки.
+// Читается из JSON или YAML.
+type Scenario struct {
+	Grid      ScenarioGrid      `json:"grid" yaml:"grid"`
+	Obstacles ScenarioObstacles `json:"obstacles" yaml:"obstacles"`
+	Terrain   []TerrainRegion   `json:"terrain" yaml:"terrain"`
+	Movement  int               `json:"movement" yaml:"movement"`   // 4 или 8, по умолчанию 4
+	Heuristic string            `json:"heuristic" yaml:"heuristic"` // по умолчанию - по модели движения
+	Queue     string            `json:"queue" yaml:"queue"`         // реализация открытого списка
+	Queries   []ScenarioQuery   `json:"queries" yaml:"queries"`
+	Render    ScenarioRender    `json:"render" yaml:"render"`
+}
+
+type ScenarioGrid struct {
+	Width  int `json:"width" yaml:"width"`
+	Height int `json:"height" yaml:"height"`
+}
+
+// ScenarioRect - прямоугольник клеток с левым верхним углом (X, Y)
+type ScenarioRect struct {
+	X      int `json:"x" yaml:"x"`
+	Y      int `json:"y" yaml:"y"`
+	Width  int `json:"width" yaml:"width"`
+	Height int `json:"height" yaml:"height"`
+}
+
+// ScenarioLine - отрезок клеток между двумя точками включительно
+type ScenarioLine struct {
+	From [2]int `json:"from" yaml:"from"`
+	To   [2]int `json:"to" yaml:"to"`
+}
+
+// ScenarioRandom - случайные препятствия с заданной плотностью
+type ScenarioRandom struct {
+	Density float64 `json:"density" yaml:"density"`
+	Seed    int64   `json:"seed" yaml:"seed"`
+}
+
+type ScenarioObstacles struct {
+	Random *ScenarioRandom `json:"random" yaml:"random"`
+	Rects  []ScenarioRect  `json:"rects" yaml:"rects"`
+	Lines  []ScenarioLine  `json:"lines" yaml:"lines"`
+	Points [][2]int        `json:"points" yaml:"points"`
+}
+
+// TerrainRegion задает стоимость клеток прямоугольника
+type TerrainRegion struct {
+	Rect ScenarioRect `json:"rect" yaml:"rect"`
+	Cost float64      `json:"cost" yaml:"cost"`
+}
+
+type ScenarioQuery struct {
+	Name  string `json:"name" yaml:"name"`
+	Start [2]int `json:"start" yaml:"start"`
+	Goal  [2]int `json:"goal" yaml:"goal"`
+}
+
+type ScenarioRender struct {
+	Output   string `json:"output" yaml:"output"`     // файл PlotGrid; пусто - без графики
+	Detailed bool   `json:"detailed" yaml:"detailed"` // PlotGridDetailed вместо PlotGrid
+	Terminal bool   `json:"terminal" yaml:"terminal"` // вывести карту в терминал
+	Overlay  string `json:"overlay" yaml:"overlay"`   // наложение поиска на график: sets, g, order
+}
+
+// ParseScenario разбирает и проверяет сценарий; format - "json" или "yaml"
+func ParseScenario(data []byte, format string) (*Scenario, error) {
+	sc, err := decodeScenario(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// decodeScenario разбирает сценарий без проверки
+func decodeScenario(data []byte, format string) (*Scenario, error) {
+	sc := &Scenario{}
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(sc); err != nil {
+			return nil, jsonErrorPosition(data, err)
+		}
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(sc); err != nil && err != io.EOF {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown scenario format %q", format)
+	}
+	return sc, nil
+}
+
+// jsonErrorPosition дополняет ошибку JSON номером строки и столбца
+func jsonErrorPosition(data []byte, err error) error {
+	var offset int64 = -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		return err
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	col := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+	return fmt.Errorf("line %d, column %d: %w", line, col, err)
+}
+
+// LoadScenario читает сценарий, формат определяется расширением файла
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := ""
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = "json"
+	case ".yaml", ".yml":
+		format = "yaml"
+	default:
+		return nil, fmt.Errorf("%s: scenario must be .json, .yaml or .yml", path)
+	}
+
+	sc, err := ParseScenario(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// Validate проверяет сценарий и возвращает все найденные ошибки сразу
+func (sc *Scenario) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	w, h := sc.Grid.Width, sc.Grid.Height
+	if err := checkGridSize(w, h); err != nil {
+		fail("grid: %v", err)
+		return errors.Join(errs...)
+	}
+	inside := func(field string, p [2]int) {
+		if p[0] < 0 || p[0] >= w || p[1] < 0 || p[1] >= h {
+			fail("%s: point (%d,%d) is outside the %dx%d grid", field, p[0], p[1], w, h)
+		}
+	}
+	rect := func(field string, r ScenarioRect) {
+		if r.Width <= 0 || r.Height <= 0 {
+			fail("%s: width and height must be positive, got %dx%d", field, r.Width, r.Height)
+			return
+		}
+		// Сравнение без сложения: огромные размеры не переполняют int
+		if r.X < 0 || r.Y < 0 || r.X > w-r.Width || r.Y > h-r.Height {
+			fail("%s: rectangle (%d,%d) %dx%d extends beyond the %dx%d grid", field, r.X, r.Y, r.Width, r.Height, w, h)
+		}
+	}
+
+	if r := sc.Obstacles.Random; r != nil && (r.Density < 0 || r.Density > 1) {
+		fail("obstacles.random.density: must be within [0, 1], got %g", r.Density)
+	}
+	for i, r := range sc.Obstacles.Rects {
+		rect(fmt.Sprintf("obstacles.rects[%d]", i), r)
+	}
+	for i, l := range sc.Obstacles.Lines {
+		inside(fmt.Sprintf("obstacles.lines[%d].from", i), l.From)
+		inside(fmt.Sprintf("obstacles.lines[%d].to", i), l.To)
+	}
+	for i, p := range sc.Obstacles.Points {
+		inside(fmt.Sprintf("obstacles.points[%d]", i), p)
+	}
+	for i, t := range sc.Terrain {
+		rect(fmt.Sprintf("terrain[%d].rect", i), t.Rect)
+		if !validCost(t.Cost) || t.Cost > maxTerrainCost {
+			fail("terrain[%d].cost: must be between 1 and %g, got %g", i, maxTerrainCost, t.Cost)
+		}
+	}
+
+	if sc.Movement != 0 && sc.Movement != 4 && sc.Movement != 8 {
+		fail("movement: must be 4 or 8, got %d", sc.Movement)
+	}
+	if sc.Heuristic != "" {
+		if _, err := ParseHeuristic(sc.Heuristic); err != nil {
+			fail("heuristic: %v", err)
+		}
+	}
+	if sc.Queue != "" {
+		if _, err := ParseQueueKind(sc.Queue); err != nil {
+			fail("queue: %v", err)
+		}
+	}
+
+	if len(sc.Queries) == 0 {
+		fail("queries: at least one start/goal pair is required")
+	}
+	for i, q := range sc.Queries {
+		inside(fmt.Sprintf("queries[%d].start", i), q.Start)
+		inside(fmt.Sprintf("queries[%d].goal", i), q.Goal)
+	}
+
+	if out := sc.Render.Output; out != "" {
+		switch strings.ToLower(filepath.Ext(out)) {
+		case ".png", ".svg", ".pdf", ".jpg", ".jpeg", ".eps", ".tif", ".tiff":
+		default:
+			fail("render.output: unsupported image format %q", filepath.Ext(out))
+		}
+	}
+	if sc.Render.Overlay != "" {
+		if _, err := ParseOverlay(sc.Render.Overlay); err != nil {
+			fail("render.overlay: %v", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Концы запросов не должны оказаться под явно заданными препятствиями
+	grid := sc.BuildGrid()
+	for i, q := range sc.Queries {
+		for _, end := range []struct {
+			name string
+			p    [2]int
+		}{{"start", q.Start}, {"goal", q.Goal}} {
+			if grid.IsObstacle(Point{end.p[0], end.p[1]}) {
+				fail("queries[%d].%s: point (%d,%d) is covered by an obstacle", i, end.name, end.p[0], end.p[1])
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// bresenham обходит клетки отрезка от from до to включительно
+func bresenham(from, to Point, visit func(Point)) {
+	dx, dy := abs(to.x-from.x), -abs(to.y-from.y)
+	sx, sy := 1, 1
+	if from.x > to.x {
+		sx = -1
+	}
+	if from.y > to.y {
+		sy = -1
+	}
+	err := dx + dy
+	for p := from; ; {
+		visit(p)
+		if p == to {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			p.x += sx
+		} else {
+			err += dx
+			p.y += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// BuildGrid строит сетку сценария, прошедшего Validate. Случайные
+// препятствия никогда не ставятся на концы запросов.
+func (sc *Scenario) BuildGrid() *Grid {
+	grid := NewGrid(sc.Grid.Width, sc.Grid.Height)
+
+	if r := sc.Obstacles.Random; r != nil {
+		reserved := make(map[Point]bool)
+		for _, q := range sc.Queries {
+			reserved[Point{q.Start[0], q.Start[1]}] = true
+			reserved[Point{q.Goal[0], q.Goal[1]}] = true
+		}
+		rng := rand.New(rand.NewSource(r.Seed))
+		for y := 0; y < grid.Height; y++ {
+			for x := 0; x < grid.Width; x++ {
+				if rng.Float64() < r.Density && !reserved[Point{x, y}] {
+					grid.AddObstacle(Point{x, y})
+				}
+			}
+		}
+	}
+	for _, r := range sc.Obstacles.Rects {
+		for y := r.Y; y < r.Y+r.Height; y++ {
+			for x := r.X; x < r.X+r.Width; x++ {
+				grid.AddObstacle(Point{x, y})
+			}
+		}
+	}
+	for _, l := range sc.Obstacles.Lines {
+		grid.DrawLine(Point{l.From[0], l.From[1]}, Point{l.To[0], l.To[1]}, true)
+	}
+	for _, p := range sc.Obstacles.Points {
+		grid.AddObstacle(Point{p[0], p[1]})
+	}
+	for _, t := range sc.Terrain {
+		for y := t.Rect.Y; y < t.Rect.Y+t.Rect.Height; y++ {
+			for x := t.Rect.X; x < t.Rect.X+t.Rect.Width; x++ {
+				grid.SetCost(Point{x, y}, t.Cost)
+			}
+		}
+	}
+	return grid
+}
+
+// NewSearcher создает Searcher для сетки с параметрами поиска сценария
+func (sc *Scenario) NewSearcher(grid *Grid) *Searcher {
+	searcher := NewSearcher(grid)
+	if sc.Movement == 8 {
+		searcher.SetMovement(Moves8)
+	}
+	if sc.Heuristic != "" {
+		h, _ := ParseHeuristic(sc.Heuristic)
+		searcher.SetHeuristic(h)
+	}
+	if sc.Queue != "" {
+		kind, _ := ParseQueueKind(sc.Queue)
+		searcher.SetQueue(NewPriorityQueue(kind))
+	}
+	return searcher
+}
+
+// Run решает все запросы сценария, печатает маршруты и сохраняет графику
+func (sc *Scenario) Run(w io.Writer) error {
+	grid := sc.BuildGrid()
+	searcher := sc.NewSearcher(grid)
+
+	failed := 0
+	for i, q := range sc.Queries {
+		name := q.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		start, goal := Point{q.Start[0], q.Start[1]}, Point{q.Goal[0], q.Goal[1]}
+
+		fmt.Fprintf(w, "Запрос %s: (%d,%d) -> (%d,%d)\n", name, start.x, start.y, goal.x, goal.y)
+		path, err := searcher.Search(start, goal)
+		if err != nil {
+			fmt.Fprintf(w, "Ошибка: %v\n", err)
+			failed++
+			continue
+		}
+
+		stats := searcher.Stats()
+		fmt.Fprintf(w, "Путь найден! Длина пути: %d шагов, стоимость %.2f, раскрыто узлов: %d\n",
+			len(path)-1, path[len(path)-1].GCost, stats.Expanded)
+		fmt.Fprint(w, "Маршрут: ")
+		for j, node := range path {
+			if j > 0 {
+				fmt.Fprint(w, " -> ")
+			}
+			fmt.Fprintf(w, "(%d,%d)", node.Position.x, node.Position.y)
+		}
+		fmt.Fprintln(w)
+
+		if sc.Render.Terminal {
+			view := TerminalView{Path: path, Open: searcher.OpenSet(), Closed: searcher.ClosedSet()}
+			if err := RenderTerminal(w, grid, view); err != nil {
+				return err
+			}
+		}
+
+		if out := sc.Render.Output; out != "" {
+			if len(sc.Queries) > 1 {
+				ext := filepath.Ext(out)
+				out = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(out, ext), i+1, ext)
+			}
+			plot := PlotGrid
+			if sc.Render.Detailed {
+				plot = PlotGridDetailed
+			}
+			opts := RenderOptions{Start: &start, Goal: &goal}
+			if sc.Render.Overlay != "" {
+				opts.Overlay, _ = ParseOverlay(sc.Render.Overlay)
+				opts.Trace = searcher.Trace()
+			}
+			if err := plot(grid, path, out, opts); err != nil {
+				return fmt.Errorf("render %s: %w", out, err)
+			}
+			fmt.Fprintf(w, "График сохранен как: %s\n", out)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queries have no path", failed, len(sc.Queries))
+	}
+	return nil
+}
+
+// MapFile возвращает сетку сценария с концами первого запроса
+func (sc *Scenario) MapFile() *MapFile {
+	m := &MapFile{Grid: sc.BuildGrid(), Scenario: sc}
+	if len(sc.Queries) > 0 {
+		q := sc.Queries[0]
+		m.Start = &Point{q.Start[0], q.Start[1]}
+		m.Goal = &Point{q.Goal[0], q.Goal[1]}
+	}
+	return m
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// scenarioYAML собирает сценарий 10x10 с одним запросом и добавленными
+// строками YAML
+func scenarioYAML(extra string) []byte {
+	return []byte("grid: {width: 10, height: 10}\nqueries:\n  - {start: [0, 0], goal: [9, 9]}\n" + extra)
+}
+
+func TestScenarioValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []string // подстроки ошибки; пусто - сценарий верен
+	}{
+		{"valid", scenarioYAML("terrain:\n  - {rect: {x: 1, y: 1, width: 2, height: 2}, cost: 5}\n"), nil},
+		{"max terrain cost", scenarioYAML("terrain:\n  - {rect: {x: 1, y: 1, width: 2, height: 2}, cost: 1e6}\n"), nil},
+		{"empty grid", []byte("grid: {width: 0, height: 5}\n"), []string{"grid: grid size must be positive, got 0x5"}},
+		{"huge grid", []byte("grid: {width: 100000, height: 100000}\n"), []string{"grid: grid 100000x100000 exceeds the limit"}},
+		{"no queries", []byte("grid: {width: 3, height: 3}\n"), []string{"queries: at least one start/goal pair is required"}},
+		{"query outside", []byte("grid: {width: 3, height: 3}\nqueries:\n  - {start: [0, 0], goal: [3, 1]}\n"),
+			[]string{"queries[0].goal: point (3,1) is outside the 3x3 grid"}},
+		{"point outside", scenarioYAML("obstacles:\n  points: [[1, 1], [-1, 4]]\n"),
+			[]string{"obstacles.points[1]: point (-1,4) is outside the 10x10 grid"}},
+		{"line outside", scenarioYAML("obstacles:\n  lines:\n    - {from: [0, 5], to: [10, 5]}\n"),
+			[]string{"obstacles.lines[0].to: point (10,5) is outside the 10x10 grid"}},
+		{"empty rect", scenarioYAML("obstacles:\n  rects:\n    - {x: 1, y: 1, width: 0, height: 3}\n"),
+			[]string{"obstacles.rects[0]: width and height must be positive, got 0x3"}},
+		{"rect beyond", scenarioYAML("obstacles:\n  rects:\n    - {x: 8, y: 1, width: 3, height: 3}\n"),
+			[]string{"obstacles.rects[0]: rectangle (8,1) 3x3 extends beyond the 10x10 grid"}},
+		{"density", scenarioYAML("obstacles:\n  random: {density: 1.5}\n"),
+			[]string{"obstacles.random.density: must be within [0, 1], got 1.5"}},
+		{"cheap terrain", scenarioYAML("terrain:\n  - {rect: {x: 1, y: 1, width: 2, height: 2}, cost: 0.5}\n"),
+			[]string{"terrain[0].cost: must be between 1 and 1e+06, got 0.5"}},
+		{"NaN terrain", scenarioYAML("terrain:\n  - {rect: {x: 1, y: 1, width: 2, height: 2}, cost: .nan}\n"),
+			[]string{"terrain[0].cost: must be between 1 and 1e+06, got NaN"}},
+		{"infinite terrain", scenarioYAML("terrain:\n  - {rect: {x: 1, y: 1, width: 2, height: 2}, cost: .inf}\n"),
+			[]string{"terrain[0].cost: must be between 1 and 1e+06, got +Inf"}},
+		{"huge terrain", scenarioYAML("terrain:\n  - {rect: {x: 1, y: 1, width: 2, height: 2}, cost: 1e300}\n"),
+			[]string{"terrain[0].cost: must be between 1 and 1e+06, got 1e+300"}},
+		{"movement", scenarioYAML("movement: 6\n"), []string{"movement: must be 4 or 8, got 6"}},
+		{"heuristic", scenarioYAML("heuristic: taxicab\n"), []string{`heuristic: unknown heuristic "taxicab"`}},
+		{"queue", scenarioYAML("queue: fibonacci\n"), []string{`queue: unknown queue "fibonacci"`}},
+		{"render output", scenarioYAML("render: {output: map.bmp}\n"), []string{`render.output: unsupported image format ".bmp"`}},
+		{"overlay", scenarioYAML("render: {overlay: heat}\n"), []string{`render.overlay: unknown overlay "heat"`}},
+		{"end under obstacle", scenarioYAML("obstacles:\n  rects:\n    - {x: 8, y: 8, width: 2, height: 2}\n"),
+			[]string{"queries[0].goal: point (9,9) is covered by an obstacle"}},
+		{"all errors at once", scenarioYAML("movement: 3\nterrain:\n  - {rect: {x: 0, y: 0, width: 1, height: 1}, cost: 0}\n"),
+			[]string{"movement: must be 4 or 8, got 3", "terrain[0].cost: must be between 1 and 1e+06, got 0"}},
+	}
+	for _, tt := range tests {
+		_, err := ParseScenario(tt.data, "yaml")
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: no error, want %q", tt.name, tt.want)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q doesn't contain %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+// TestScenarioJSONTerrain проверяет ту же границу стоимости в JSON, где
+// NaN и бесконечность не записать, а 1e300 - можно
+func TestScenarioJSONTerrain(t *testing.T) {
+	data := []byte(`{"grid": {"width": 4, "height": 4},
+"terrain": [{"rect": {"x": 0, "y": 0, "width": 2, "height": 2}, "cost": 1e300}],
+"queries": [{"start": [0, 0], "goal": [3, 3]}]}`)
+	_, err := ParseScenario(data, "json")
+	if err == nil || !strings.Contains(err.Error(), "terrain[0].cost: must be between 1 and 1e+06, got 1e+300") {
+		t.Errorf("got %v, want a terrain cost error", err)
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Ошибки поиска; Search оборачивает их, проверять следует через errors.Is
+var (
+	ErrInvalidPoint = errors.New("point isn't available") // вне сетки или на препятствии
+	ErrNoPath       = errors.New("путь отсутствует")
+	ErrSearchLimit  = errors.New("search limit reached") // см. SetExpandLimit
+)
+
+// Space - поле клеток, по которому ищет Searcher: Grid, ChunkedGrid или
+// своя реализация. Координаты могут быть любыми, в том числе
+// отрицательными.
+type Space interface {
+	// IsValid сообщает, можно ли стоять в клетке
+	IsValid(point Point) bool
+	// StepCost возвращает стоимость шага в соседнюю клетку и false,
+	// если шаг недопустим
+	StepCost(from, to Point) (float64, bool)
+}
+
+// cancelCheckInterval - через сколько раскрытий SearchContext проверяет
+// отмену контекста
+const cancelCheckInterval = 1024
+
+// Состояние клетки в рамках одного поиска
+const (
+	cellUnseen uint8 = iota
+	cellOpen
+	cellClosed
+)
+
+// directions4 - смещения для 4-связного движения
+var directions4 = [4][2]int{
+	{0, 1},  // вверх
+	{0, -1}, // вниз
+	{1, 0},  // вправо
+	{-1, 0}, // влево
+}
+
+// SearchStats - счетчики последнего поиска
+type SearchStats struct {
+	Expanded int `json:"expanded"` // узлов извлечено из открытого списка и раскрыто
+	Pushed   int `json:"pushed"`   // узлов добавлено в открытый список
+	Updated  int `json:"updated"`  // узлов с уменьшенной стоимостью
+	MaxOpen  int `json:"max_open"` // наибольший размер открытого списка
+}
+
+// sparsePageSize - узлов в странице пула для пространств без Grid
+const sparsePageSize = 1024
+
+// Searcher - переиспользуемый контекст поиска A* для одной сетки.
+// Пул узлов, открытый список и буфер пути выделяются один раз и
+// сбрасываются за O(1) между запросами с помощью счетчика поколений.
+// Для Grid пулы выделяются под всю сетку, для других пространств клетки
+// нумеруются по мере посещения и пулы растут страницами.
+// Searcher не безопасен для одновременного использования из нескольких горутин.
+type Searcher struct {
+	space Space
+	grid  *Grid           // space, если это Grid: индексы клеток и Trace
+	ids   map[Point]int32 // индексы посещенных клеток, если grid == nil
+	nodes []Node          // пул узлов, по одному на клетку сетки
+	pages [][]Node        // пул узлов страницами, если grid == nil
+	stamp []uint32        // поколение, в котором узел клетки был инициализирован
+	state []uint8         // состояние клетки, действительно только если stamp == gen
+	order []int32         // номер раскрытия клетки, -1 - не раскрыта; действителен при stamp == gen
+	gen   uint32
+	queue PriorityQueue
+	path  []*Node
+
+	movement  Movement
+	heuristic Heuristic // nil - эвристика по умолчанию для movement
+	algorithm Algorithm
+	weight    float64 // вес эвристики для AlgorithmWeighted
+	limit     int     // наибольшее число раскрытий, 0 - без ограничения
+	stats     SearchStats
+	observer  SearchObserver
+}
+
+// NewSearcher создает контекст поиска по space с открытым списком на
+// бинарной куче; для Grid пулы сразу выделяются под размер сетки
+func NewSearcher(space Space) *Searcher {
+	s := &Searcher{space: space, queue: &BinaryHeap{}}
+	if grid, ok := space.(*Grid); ok {
+		s.grid = grid
+	} else {
+		s.ids = make(map[Point]int32)
+	}
+	s.allocate()
+	return s
+}
+
+// SetQueue заменяет реализацию открытого списка для последующих поисков
+func (s *Searcher) SetQueue(queue PriorityQueue) {
+	s.queue = queue
+}
+
+// SetMovement задает модель перемещения
+func (s *Searcher) SetMovement(movement Movement) {
+	s.movement = movement
+}
+
+// SetHeuristic задает эвристику; nil возвращает эвристику модели движения
+func (s *Searcher) SetHeuristic(heuristic Heuristic) {
+	s.heuristic = heuristic
+}
+
+// SetAlgorithm задает вариант поиска; weight используется только
+// взвешенным A* и должен быть не меньше 1
+func (s *Searcher) SetAlgorithm(algorithm Algorithm, weight float64) {
+	s.algorithm = algorithm
+	s.weight = weight
+}
+
+// SetExpandLimit ограничивает число раскрытий одного поиска: при
+// достижении предела поиск возвращает ErrSearchLimit. Нужен для
+// неограниченных пространств, где отсутствие пути иначе не обнаружить;
+// 0 снимает ограничение.
+func (s *Searcher) SetExpandLimit(limit int) {
+	s.limit = max(0, limit)
+}
+
+// SetObserver подключает наблюдателя событий поиска; nil отключает его
+func (s *Searcher) SetObserver(observer SearchObserver) {
+	s.observer = observer
+}
+
+// priority вычисляет FCost узла в зависимости от варианта поиска
+func (s *Searcher) priority(g, h float64) float64 {
+	switch s.algorithm {
+	case AlgorithmGreedy:
+		return h
+	case AlgorithmWeighted:
+		return g + s.weight*h
+	default:
+		return g + h
+	}
+}
+
+// Stats возвращает счетчики последнего поиска
+func (s *Searcher) Stats() SearchStats {
+	return s.stats
+}
+
+// allocate (пере)выделяет пулы под текущий размер сетки
+func (s *Searcher) allocate() {
+	if s.grid == nil {
+		s.pages, s.stamp, s.state, s.order = nil, nil, nil, nil
+		s.gen = 0
+		return
+	}
+	size := s.grid.Width * s.grid.Height
+	s.nodes = make([]Node, size)
+	s.stamp = make([]uint32, size)
+	s.state = make([]uint8, size)
+	s.order = make([]int32, size)
+	s.gen = 0
+}
+
+// reset начинает новое поколение: все клетки снова считаются непосещенными
+func (s *Searcher) reset() {
+	if s.grid == nil {
+		clear(s.ids)
+	} else if len(s.nodes) != s.grid.Width*s.grid.Height {
+		s.allocate()
+	}
+	s.gen++
+	if s.gen == 0 {
+		// Счетчик переполнился - старые отметки могут совпасть с новыми
+		clear(s.stamp)
+		s.gen = 1
+	}
+	s.queue.Reset()
+	s.path = s.path[:0]
+	s.stats = SearchStats{}
+}
+
+// index возвращает индекс клетки в пулах; клетке пространства без Grid
+// индекс выдается при первом посещении в текущем поиске
+func (s *Searcher) index(point Point) int {
+	if s.grid != nil {
+		return s.grid.index(point)
+	}
+	i, ok := s.ids[point]
+	if !ok {
+		i = int32(len(s.ids))
+		s.ids[point] = i
+		if int(i) == len(s.stamp) {
+			if int(i)%sparsePageSize == 0 {
+				s.pages = append(s.pages, make([]Node, sparsePageSize))
+			}
+			s.stamp = append(s.stamp, 0)
+			s.state = append(s.state, cellUnseen)
+			s.order = append(s.order, -1)
+		}
+	}
+	return int(i)
+}
+
+// nodeAt возвращает узел пула по индексу клетки
+func (s *Searcher) nodeAt(i int) *Node {
+	if s.grid != nil {
+		return &s.nodes[i]
+	}
+	return &s.pages[i/sparsePageSize][i%sparsePageSize]
+}
+
+// node возвращает узел клетки из пула и его индекс, инициализируя узел
+// в текущем поколении
+func (s *Searcher) node(point Point) (*Node, int) {
+	i := s.index(point)
+	n := s.nodeAt(i)
+	if s.stamp[i] != s.gen {
+		s.stamp[i] = s.gen
+		s.state[i] = cellUnseen
+		s.order[i] = -1
+		*n = Node{Position: point, Index: -1}
+	}
+	return n, i
+}
+
+// Search ищет путь от start до goal.
+// Возвращаемый путь и его узлы принадлежат Searcher и действительны
+// только до следующего вызова Search.
+func (s *Searcher) Search(start, goal Point) ([]*Node, error) {
+	return s.SearchContext(context.Background(), start, goal)
+}
+
+// SearchContext ищет путь как Search, но прерывает поиск и возвращает
+// ошибку контекста, если ctx отменен или истек его срок. Контекст
+// проверяется раз в cancelCheckInterval раскрытий; для контекста без
+// отмены проверок нет совсем.
+func (s *Searcher) SearchContext(ctx context.Context, start, goal Point) ([]*Node, error) {
+	space := s.space
+	s.stats = SearchStats{}
+	if !space.IsValid(start) {
+		return nil, fmt.Errorf("start %w: (%d,%d)", ErrInvalidPoint, start.x, start.y)
+	}
+	if !space.IsValid(goal) {
+		return nil, fmt.Errorf("goal %w: (%d,%d)", ErrInvalidPoint, goal.x, goal.y)
+	}
+	done := ctx.Done()
+	if done != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.reset()
+
+	heuristic := s.heuristic
+	if heuristic == nil {
+		heuristic = s.movement.DefaultHeuristic()
+	}
+	if s.algorithm == AlgorithmDijkstra {
+		heuristic = Zero
+	}
+	directions := s.movement.directions()
+
+	startNode, i := s.node(start)
+	startNode.HCost = heuristic(start, goal)
+	startNode.FCost = s.priority(0, startNode.HCost)
+	s.state[i] = cellOpen
+	s.queue.Push(startNode)
+	s.stats.Pushed++
+	s.stats.MaxOpen = 1
+	if s.observer != nil {
+		s.observer.OnPush(startNode)
+	}
+
+	for s.queue.Len() > 0 {
+		if s.limit > 0 && s.stats.Expanded >= s.limit {
+			return nil, fmt.Errorf("%w: %d nodes expanded from (%d,%d) to (%d,%d)", ErrSearchLimit, s.stats.Expanded, start.x, start.y, goal.x, goal.y)
+		}
+		current := s.queue.Pop()
+		cur := s.index(current.Position)
+		s.order[cur] = int32(s.stats.Expanded)
+		s.stats.Expanded++
+		if done != nil && s.stats.Expanded%cancelCheckInterval == 0 {
+			select {
+			case <-done:
+				return nil, ctx.Err()
+			default:
+			}
+		}
+		if s.observer != nil {
+			s.observer.OnPop(current)
+		}
+
+		if current.Position == goal {
+			path := s.reconstruct(current)
+			if s.observer != nil {
+				s.observer.OnGoal(path)
+			}
+			return path, nil
+		}
+
+		s.state[cur] = cellClosed
+		if s.observer != nil {
+			s.observer.OnClose(current)
+		}
+
+		for _, dir := range directions {
+			next := Point{current.Position.x + dir[0], current.Position.y + dir[1]}
+			step, ok := space.StepCost(current.Position, next)
+			if !ok {
+				continue
+			}
+
+			neighbor, j := s.node(next)
+			state := s.state[j]
+			if state == cellClosed {
+				continue
+			}
+
+			tentativeG := current.GCost + step
+
+			if state == cellUnseen {
+				neighbor.GCost = tentativeG
+				neighbor.HCost = heuristic(next, goal)
+				neighbor.FCost = s.priority(neighbor.GCost, neighbor.HCost)
+				neighbor.Parent = current
+				s.state[j] = cellOpen
+
+				s.queue.Push(neighbor)
+				s.stats.Pushed++
+				// Открытые узлы - добавленные и еще не извлеченные
+				s.stats.MaxOpen = max(s.stats.MaxOpen, s.stats.Pushed-s.stats.Expanded)
+				if s.observer != nil {
+					s.observer.OnPush(neighbor)
+				}
+			} else if tentativeG < neighbor.GCost {
+				neighbor.GCost = tentativeG
+				neighbor.FCost = s.priority(neighbor.GCost, neighbor.HCost)
+				neighbor.Parent = current
+
+				s.queue.Update(neighbor)
+				s.stats.Updated++
+				if s.observer != nil {
+					s.observer.OnUpdate(neighbor)
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: от (%d,%d) до (%d,%d)", ErrNoPath, start.x, start.y, goal.x, goal.y)
+}
+
+// reconstruct собирает путь в переиспользуемый буфер
+func (s *Searcher) reconstruct(node *Node) []*Node {
+	for current := node; current != nil; current = current.Parent {
+		s.path = append(s.path, current)
+	}
+	for i, j := 0, len(s.path)-1; i < j; i, j = i+1, j-1 {
+		s.path[i], s.path[j] = s.path[j], s.path[i]
+	}
+	return s.path
+}
+
+// cellsInState перечисляет клетки в заданном состоянии после последнего поиска
+func (s *Searcher) cellsInState(state uint8) []Point {
+	var cells []Point
+	for i := range s.stamp {
+		if s.stamp[i] == s.gen && s.state[i] == state {
+			cells = append(cells, s.nodeAt(i).Position)
+		}
+	}
+	return cells
+}
+
+// OpenSet возвращает клетки, оставшиеся в открытом списке после последнего поиска
+func (s *Searcher) OpenSet() []Point {
+	return s.cellsInState(cellOpen)
+}
+
+// ClosedSet возвращает клетки, раскрытые в последнем поиске
+func (s *Searcher) ClosedSet() []Point {
+	return s.cellsInState(cellClosed)
+}
+
+// SearchTrace - снимок состояния последнего поиска для визуализации.
+// Срезы GCost и Order индексируются как y*Width+x; для пространств без
+// Grid они пусты, а Width и Height равны 0.
+type SearchTrace struct {
+	Width, Height int
+	Open, Closed  []Point
+	// GCost - стоимость от старта; NaN - клетка не достигнута
+	GCost []float64
+	// Order - номер раскрытия клетки начиная с 0; -1 - клетка не раскрыта
+	Order []int32
+}
+
+// Trace копирует открытый и закрытый списки, стоимости от старта
+// и порядок раскрытия клеток последнего поиска
+func (s *Searcher) Trace() *SearchTrace {
+	if s.grid == nil {
+		return &SearchTrace{Open: s.OpenSet(), Closed: s.ClosedSet()}
+	}
+	size := len(s.nodes)
+	trace := &SearchTrace{
+		Width:  s.grid.Width,
+		Height: s.grid.Height,
+		Open:   s.OpenSet(),
+		Closed: s.ClosedSet(),
+		GCost:  make([]float64, size),
+		Order:  make([]int32, size),
+	}
+	for i := range s.nodes {
+		if s.stamp[i] != s.gen || s.state[i] == cellUnseen {
+			trace.GCost[i] = math.NaN()
+			trace.Order[i] = -1
+			continue
+		}
+		trace.GCost[i] = s.nodes[i].GCost
+		trace.Order[i] = s.order[i]
+	}
+	return trace
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerOptions - ограничения HTTP-сервиса поиска пути
+type ServerOptions struct {
+	// MaxBodyBytes - наибольший размер тела запроса; 0 - 16 МиБ
+	MaxBodyBytes int64
+	// MaxCells - наибольшее число клеток загружаемой сетки; 0 - 16M
+	MaxCells int
+	// Timeout - время поиска, если запрос не задает timeout_ms; 0 - 5 с
+	Timeout time.Duration
+	// MaxTimeout ограничивает timeout_ms запроса; 0 - 1 мин
+	MaxTimeout time.Duration
+	// Metrics, если не nil, учитывает поиски и отдается на GET /metrics
+	Metrics *SearchMetrics
+}
+
+func (o ServerOptions) withDefaults() ServerOptions {
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = 16 << 20
+	}
+	if o.MaxCells <= 0 {
+		o.MaxCells = 16 << 20
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.MaxTimeout <= 0 {
+		o.MaxTimeout = time.Minute
+	}
+	return o
+}
+
+// Server - HTTP-сервис поиска пути по именованным сеткам:
+//
+//	GET    /grids                 список сеток
+//	PUT    /grids/{name}          загрузить сетку (?format=ascii|map|image|json|yaml)
+//	GET    /grids/{name}          описание сетки
+//	DELETE /grids/{name}          удалить сетку
+//	POST   /grids/{name}/edits    добавить и убрать препятствия, задать стоимости
+//	POST   /grids/{name}/path     найти путь
+//	GET    /metrics               метрики OpenMetrics, если заданы в ServerOptions
+//
+// Ответы - JSON; ошибки - {"error": "..."} с кодом HTTP. Поиски по одной
+// сетке выполняются параллельно, правки ждут их завершения.
+type Server struct {
+	opts  ServerOptions
+	mux   *http.ServeMux
+	mu    sync.RWMutex
+	grids map[string]*serverGrid
+}
+
+// serverGrid - сетка сервиса с пулом Searcher для параллельных поисков
+type serverGrid struct {
+	mu          sync.RWMutex // поиски берут на чтение, правки - на запись
+	grid        *Grid
+	start, goal *Point // концы по умолчанию из загруженной карты
+	version     uint64 // увеличивается при каждой правке
+	searchers   sync.Pool
+}
+
+// NewServer создает сервис без сеток
+func NewServer(opts ServerOptions) *Server {
+	s := &Server{
+		opts:  opts.withDefaults(),
+		mux:   http.NewServeMux(),
+		grids: make(map[string]*serverGrid),
+	}
+	s.mux.HandleFunc("GET /grids", s.handleList)
+	s.mux.HandleFunc("PUT /grids/{name}", s.handlePut)
+	s.mux.HandleFunc("GET /grids/{name}", s.handleGet)
+	s.mux.HandleFunc("DELETE /grids/{name}", s.handleDelete)
+	s.mux.HandleFunc("POST /grids/{name}/edits", s.handleEdits)
+	s.mux.HandleFunc("POST /grids/{name}/path", s.handlePath)
+	if s.opts.Metrics != nil {
+		s.mux.Handle("GET /metrics", s.opts.Metrics)
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// AddGrid регистрирует карту под именем name, заменяя прежнюю.
+// Сетка переходит во владение сервиса и не должна меняться снаружи.
+func (s *Server) AddGrid(name string, m *MapFile) {
+	s.addGrid(name, m)
+}
+
+func (s *Server) addGrid(name string, m *MapFile) (g *serverGrid, replaced bool) {
+	g = &serverGrid{grid: m.Grid, start: m.Start, goal: m.Goal}
+	g.searchers.New = func() any { return NewSearcher(g.grid) }
+	s.mu.Lock()
+	_, replaced = s.grids[name]
+	s.grids[name] = g
+	s.mu.Unlock()
+	return g, replaced
+}
+
+// gridByName возвращает сетку по имени, nil - сетки нет
+func (s *Server) gridByName(name string) *serverGrid {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.grids[name]
+}
+
+// lookup возвращает сетку из пути запроса или отвечает 404
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *serverGrid {
+	name := r.PathValue("name")
+	g := s.gridByName(name)
+	if g == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("grid %q not found", name))
+	}
+	return g
+}
+
+// gridInfo - описание сетки в ответах
+type gridInfo struct {
+	Name      string  `json:"name"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+	Obstacles int     `json:"obstacles"`
+	Weighted  bool    `json:"weighted"` // есть клетки со стоимостью не 1
+	Start     *[2]int `json:"start,omitempty"`
+	Goal      *[2]int `json:"goal,omitempty"`
+	Version   uint64  `json:"version"`
+}
+
+func (g *serverGrid) info(name string) gridInfo {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	info := gridInfo{
+		Name:     name,
+		Width:    g.grid.Width,
+		Height:   g.grid.Height,
+		Weighted: g.grid.Costs != nil,
+		Start:    pointPair(g.start),
+		Goal:     pointPair(g.goal),
+		Version:  g.version,
+	}
+	for _, o := range g.grid.Obstacles {
+		if o {
+			info.Obstacles++
+		}
+	}
+	return info
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.grids))
+	for name := range s.grids {
+		names = append(names, name)
+	}
+	grids := make([]*serverGrid, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		grids[i] = s.grids[name]
+	}
+	s.mu.RUnlock()
+
+	infos := make([]gridInfo, len(names))
+	for i, g := range grids {
+		infos[i] = g.info(names[i])
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	if g := s.lookup(w, r); g != nil {
+		writeJSON(w, http.StatusOK, g.info(r.PathValue("name")))
+	}
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s.mu.Lock()
+	_, ok := s.grids[name]
+	delete(s.grids, name)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("grid %q not found", name))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePut(w http.ResponseWriter, r *http.Request) {
+	data, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ascii"
+	}
+	m, err := parseUploadedMap(data, format, s.opts.MaxCells)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := r.PathValue("name")
+	g, replaced := s.addGrid(name, m)
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, g.info(name))
+}
+
+// parseUploadedMap разбирает карту в формате format: ascii - текстовый
+// формат, map - MovingAI, image - PNG, GIF или BMP, json и yaml - сценарий.
+// Сетки больше maxCells клеток отклоняются до выделения памяти.
+func parseUploadedMap(data []byte, format string, maxCells int) (*MapFile, error) {
+	checkSize := func(width, height int) error {
+		if width > 0 && height > 0 && width > maxCells/height {
+			return fmt.Errorf("grid %dx%d exceeds the limit of %d cells", width, height, maxCells)
+		}
+		return nil
+	}
+
+	switch format {
+	case "ascii":
+		if err := checkSize(asciiSize(data)); err != nil {
+			return nil, err
+		}
+		return ParseASCII(bytes.NewReader(data))
+	case "map":
+		width, height := movingAIHeaderSize(data)
+		if err := checkSize(width, height); err != nil {
+			return nil, err
+		}
+		grid, err := ReadMovingAIMap(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &MapFile{Grid: grid}, nil
+	case "image":
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSize(cfg.Width, cfg.Height); err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &MapFile{Grid: GridFromImage(img, ImageGridOptions{})}, nil
+	case "json", "yaml":
+		// Размер проверяется до Validate и построения сетки
+		sc, err := decodeScenario(data, format)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSize(sc.Grid.Width, sc.Grid.Height); err != nil {
+			return nil, err
+		}
+		if err := sc.Validate(); err != nil {
+			return nil, err
+		}
+		return sc.MapFile(), nil
+	}
+	return nil, fmt.Errorf("unknown map format %q (want ascii, map, image, json or yaml)", format)
+}
+
+// asciiSize оценивает размер текстовой карты сверху: ширина первой
+// строки на число строк
+func asciiSize(data []byte) (width, height int) {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	width = len(bytes.TrimRight(first, "\r"))
+	height = bytes.Count(data, []byte("\n")) + 1
+	return width, height
+}
+
+// movingAIHeaderSize читает ширину и высоту из заголовка карты MovingAI;
+// -1, если они не заданы
+func movingAIHeaderSize(data []byte) (width, height int) {
+	width, height = -1, -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[0] == "map" {
+			break
+		}
+		if len(fields) == 2 {
+			v, err := strconv.Atoi(fields[1])
+			if err != nil {
+				continue
+			}
+			switch fields[0] {
+			case "width":
+				width = v
+			case "height":
+				height = v
+			}
+		}
+	}
+	return width, height
+}
+
+// gridEdits - тело запроса правки сетки. Правки применяются целиком или
+// не применяются вовсе: сначала удаления, затем добавления, затем стоимости.
+type gridEdits struct {
+	Add    [][2]int   `json:"add"`
+	Remove [][2]int   `json:"remove"`
+	Costs  []cellCost `json:"costs"`
+}
+
+type cellCost struct {
+	Point [2]int  `json:"point"`
+	Cost  float64 `json:"cost"`
+}
+
+func (s *Server) handleEdits(w http.ResponseWriter, r *http.Request) {
+	g := s.lookup(w, r)
+	if g == nil {
+		return
+	}
+	var edits gridEdits
+	if !s.decodeJSON(w, r, &edits) {
+		return
+	}
+	if err := g.edit(edits); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, g.info(r.PathValue("name")))
+}
+
+// edit применяет правки, дождавшись завершения идущих поисков
+func (g *serverGrid) edit(edits gridEdits) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := edits.apply(g.grid); err != nil {
+		return err
+	}
+	g.version++
+	return nil
+}
+
+// apply проверяет все правки и только затем применяет их
+func (e gridEdits) apply(grid *Grid) error {
+	var errs []error
+	check := func(field string, i int, p [2]int) {
+		if !grid.InBounds(Point{p[0], p[1]}) {
+			errs = append(errs, fmt.Errorf("%s[%d]: point (%d,%d) is outside the %dx%d grid", field, i, p[0], p[1], grid.Width, grid.Height))
+		}
+	}
+	for i, p := range e.Remove {
+		check("remove", i, p)
+	}
+	for i, p := range e.Add {
+		check("add", i, p)
+	}
+	for i, c := range e.Costs {
+		check("costs", i, c.Point)
+		if !(c.Cost >= 1) || c.Cost > maxTerrainCost {
+			errs = append(errs, fmt.Errorf("costs[%d]: cost must be between 1 and %g, got %g", i, maxTerrainCost, c.Cost))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Одна транзакция - одна версия сетки и одно уведомление подписчикам
+	return grid.Update(func() error {
+		for _, p := range e.Remove {
+			grid.RemoveObstacle(Point{p[0], p[1]})
+		}
+		for _, p := range e.Add {
+			grid.AddObstacle(Point{p[0], p[1]})
+		}
+		for _, c := range e.Costs {
+			grid.SetCost(Point{c.Point[0], c.Point[1]}, c.Cost)
+		}
+		return nil
+	})
+}
+
+// maxTerrainCost - наибольшая стоимость клетки, принимаемая в правках и
+// сценариях
+const maxTerrainCost = 1e6
+
+// pathRequest - тело запроса поиска пути. Пустые поля берут значения
+// по умолчанию: концы из загруженной карты, astar, движение 4,
+// эвристика по модели движения.
+type pathRequest struct {
+	Start     *[2]int `json:"start"`
+	Goal      *[2]int `json:"goal"`
+	Algorithm string  `json:"algorithm"`
+	Weight    float64 `json:"weight"`
+	Heuristic string  `json:"heuristic"`
+	Movement  int     `json:"movement"`
+	TimeoutMS int     `json:"timeout_ms"`
+}
+
+// solver проверяет параметры запроса и возвращает настройку решателя
+func (req pathRequest) solver() (SolverConfig, error) {
+	cfg := SolverConfig{Weight: 1.5}
+	if req.Algorithm != "" {
+		alg, err := ParseAlgorithm(req.Algorithm)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Algorithm = alg
+	}
+	if req.Weight != 0 {
+		if req.Weight < 1 {
+			return cfg, fmt.Errorf("weight must be at least 1, got %g", req.Weight)
+		}
+		cfg.Weight = req.Weight
+	}
+	if req.Heuristic != "" {
+		h, err := ParseHeuristic(req.Heuristic)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Heuristic = h
+	}
+	switch req.Movement {
+	case 0, 4:
+	case 8:
+		cfg.Movement = Moves8
+	default:
+		return cfg, fmt.Errorf("unknown movement %d (want 4 or 8)", req.Movement)
+	}
+	if req.TimeoutMS < 0 {
+		return cfg, fmt.Errorf("timeout_ms must not be negative, got %d", req.TimeoutMS)
+	}
+	return cfg, nil
+}
+
+// requestError - ошибка в параметрах запроса, а не результат поиска
+type requestError struct{ error }
+
+func (e requestError) Unwrap() error { return e.error }
+
+// findPath ищет путь по запросу req на сетке g. observer, если не nil,
+// получает события поиска. Ошибка - requestError или
+// ошибка поиска; в последнем случае результат тоже заполнен.
+func (s *Server) findPath(ctx context.Context, g *serverGrid, req pathRequest, observer SearchObserver) (solveResult, error) {
+	cfg, err := req.solver()
+	if err != nil {
+		return solveResult{}, requestError{err}
+	}
+	timeout := s.opts.Timeout
+	if req.TimeoutMS > 0 {
+		timeout = min(time.Duration(req.TimeoutMS)*time.Millisecond, s.opts.MaxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	start, goal := req.Start, req.Goal
+	if start == nil {
+		start = pointPair(g.start)
+	}
+	if goal == nil {
+		goal = pointPair(g.goal)
+	}
+	if start == nil || goal == nil {
+		return solveResult{}, requestError{fmt.Errorf("start and goal are required: the grid has no markers")}
+	}
+
+	searcher := g.searchers.Get().(*Searcher)
+	defer g.searchers.Put(searcher)
+	searcher.SetMovement(cfg.Movement)
+	searcher.SetHeuristic(cfg.Heuristic)
+	searcher.SetAlgorithm(cfg.Algorithm, cfg.Weight)
+	searcher.SetObserver(observer)
+	defer searcher.SetObserver(nil)
+
+	from, to := Point{start[0], start[1]}, Point{goal[0], goal[1]}
+	begin := time.Now()
+	var path []*Node
+	if s.opts.Metrics != nil {
+		path, err = s.opts.Metrics.Search(ctx, searcher, from, to)
+	} else {
+		path, err = searcher.SearchContext(ctx, from, to)
+	}
+	elapsed := time.Since(begin)
+	result := newSolveResult(from, to, path, err, searcher.Stats(), elapsed)
+	if errors.Is(err, context.DeadlineExceeded) {
+		result.Error = fmt.Sprintf("search timed out after %v", elapsed.Round(time.Millisecond))
+	}
+	return result, err
+}
+
+func (s *Server) handlePath(w http.ResponseWriter, r *http.Request) {
+	g := s.lookup(w, r)
+	if g == nil {
+		return
+	}
+	var req pathRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := s.findPath(r.Context(), g, req, nil)
+	var badRequest requestError
+	switch {
+	case errors.As(err, &badRequest):
+		writeError(w, http.StatusBadRequest, err)
+	case err == nil, errors.Is(err, ErrNoPath):
+		writeJSON(w, http.StatusOK, result)
+	case errors.Is(err, ErrInvalidPoint):
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, result)
+	case errors.Is(err, context.Canceled):
+		// Клиент отключился - отвечать некому
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// readBody читает тело запроса не длиннее MaxBodyBytes
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+		} else {
+			writeError(w, http.StatusBadRequest, err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// decodeJSON читает тело запроса как JSON без неизвестных полей
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	data, ok := s.readBody(w, r)
+	if !ok {
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, jsonErrorPosition(data, err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testServerMap = `S...
+.##.
+...G
+`
+
+// do выполняет запрос к серверу и возвращает код ответа и тело
+func do(t *testing.T, srv http.Handler, method, target, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+// TestServerUploadLimits проверяет, что карты больше MaxCells и тела
+// больше MaxBodyBytes отклоняются до разбора
+func TestServerUploadLimits(t *testing.T) {
+	srv := NewServer(ServerOptions{MaxCells: 100, MaxBodyBytes: 4096})
+	tests := []struct {
+		name, format, body string
+		want               int
+	}{
+		{"ascii", "ascii", testServerMap, http.StatusCreated},
+		{"ascii too many cells", "ascii", strings.Repeat(strings.Repeat(".", 20)+"\n", 20), http.StatusBadRequest},
+		{"map header", "map", "type octile\nheight 1000\nwidth 1000\nmap\n", http.StatusBadRequest},
+		{"scenario", "json", `{"grid": {"width": 10, "height": 10}, "queries": [{"start": [0, 0], "goal": [9, 9]}]}`, http.StatusCreated},
+		{"scenario too many cells", "json", `{"grid": {"width": 100000, "height": 100000}}`, http.StatusBadRequest},
+		{"scenario overflow", "yaml", "grid: {width: 4294967296, height: 4294967296}\n", http.StatusBadRequest},
+		{"scenario invalid", "json", `{"grid": {"width": 5, "height": 5}, "queries": [{"start": [9, 9], "goal": [0, 0]}]}`, http.StatusBadRequest},
+		{"body too large", "ascii", strings.Repeat(".", 5000), http.StatusRequestEntityTooLarge},
+		{"unknown format", "xml", testServerMap, http.StatusBadRequest},
+	}
+	for i, tt := range tests {
+		code, body := do(t, srv, http.MethodPut, fmt.Sprintf("/grids/m%d?format=%s", i, tt.format), tt.body)
+		if code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, code, tt.want, body)
+		}
+	}
+}
+
+// TestServerPath проверяет поиск пути и коды ответов на ошибки запроса
+func TestServerPath(t *testing.T) {
+	srv := NewServer(ServerOptions{})
+	if code, body := do(t, srv, http.MethodPut, "/grids/m", testServerMap); code != http.StatusCreated {
+		t.Fatalf("upload: status %d (%s)", code, body)
+	}
+
+	code, body := do(t, srv, http.MethodPost, "/grids/m/path", `{}`)
+	if code != http.StatusOK {
+		t.Fatalf("path: status %d (%s)", code, body)
+	}
+	var result solveResult
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Found || result.Steps != 5 || result.Start != [2]int{0, 0} || result.Goal != [2]int{3, 2} {
+		t.Errorf("path: got %+v, want a 5-step path from (0,0) to (3,2)", result)
+	}
+
+	tests := []struct {
+		name, target, body string
+		want               int
+	}{
+		{"no path", "/grids/m/path", `{"goal": [1, 1]}`, http.StatusUnprocessableEntity},
+		{"outside", "/grids/m/path", `{"goal": [10, 10]}`, http.StatusUnprocessableEntity},
+		{"bad algorithm", "/grids/m/path", `{"algorithm": "nope"}`, http.StatusBadRequest},
+		{"bad movement", "/grids/m/path", `{"movement": 6}`, http.StatusBadRequest},
+		{"unknown field", "/grids/m/path", `{"speed": 1}`, http.StatusBadRequest},
+		{"bad json", "/grids/m/path", `{`, http.StatusBadRequest},
+		{"unknown grid", "/grids/other/path", `{}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code, body := do(t, srv, http.MethodPost, tt.target, tt.body); code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, code, tt.want, body)
+		}
+	}
+}
+
+// TestServerEdits проверяет, что правки меняют путь и версию сетки, а
+// ошибочные правки не применяются вовсе
+func TestServerEdits(t *testing.T) {
+	srv := NewServer(ServerOptions{})
+	do(t, srv, http.MethodPut, "/grids/m", testServerMap)
+
+	code, body := do(t, srv, http.MethodPost, "/grids/m/edits", `{"add": [[1, 0], [1, 2]]}`)
+	if code != http.StatusOK {
+		t.Fatalf("edits: status %d (%s)", code, body)
+	}
+	var info gridInfo
+	if err := json.Unmarshal([]byte(body), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Obstacles != 4 || info.Version != 1 {
+		t.Errorf("edits: got %d obstacles at version %d, want 4 at version 1", info.Obstacles, info.Version)
+	}
+	var result solveResult
+	_, body = do(t, srv, http.MethodPost, "/grids/m/path", `{}`)
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Found {
+		t.Errorf("path after edits: found %v, want no path", result.Path)
+	}
+
+	tests := []struct {
+		name, body string
+	}{
+		{"outside", `{"remove": [[1, 0]], "add": [[4, 0]]}`},
+		{"cost below 1", `{"remove": [[1, 0]], "costs": [{"point": [0, 1], "cost": 0.5}]}`},
+		{"cost too high", `{"remove": [[1, 0]], "costs": [{"point": [0, 1], "cost": 1e9}]}`},
+	}
+	for _, tt := range tests {
+		if code, body := do(t, srv, http.MethodPost, "/grids/m/edits", tt.body); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, code, http.StatusBadRequest, body)
+		}
+	}
+	_, body = do(t, srv, http.MethodGet, "/grids/m", "")
+	if err := json.Unmarshal([]byte(body), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Obstacles != 4 || info.Version != 1 {
+		t.Errorf("after rejected edits: got %d obstacles at version %d, want 4 at version 1", info.Obstacles, info.Version)
+	}
+
+	if code, _ := do(t, srv, http.MethodDelete, "/grids/m", ""); code != http.StatusNoContent {
+		t.Errorf("delete: status %d, want %d", code, http.StatusNoContent)
+	}
+	if code, _ := do(t, srv, http.MethodGet, "/grids/m", ""); code != http.StatusNotFound {
+		t.Errorf("get after delete: status %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgpdf"
+	"gonum.org/v1/plot/vg/vgsvg"
+)
+
+// cellRect - прямоугольник клеток [x0, x1) x [y0, y1)
+type cellRect struct{ x0, y0, x1, y1 int }
+
+// mergeRects покрывает клетки одинакового цвета прямоугольниками:
+// соседние клетки строки сливаются в отрезки, а одинаковые отрезки
+// соседних строк - в прямоугольники. Клетки цвета skip не покрываются.
+// Результат сгруппирован по номеру цвета.
+func mergeRects(width, height int, colorAt func(x, y int) int, skip int) map[int][]cellRect {
+	rects := make(map[int][]cellRect)
+	type run struct{ x0, x1, color int }
+	active := make(map[run]int) // отрезок -> строка, с которой он тянется
+	var row []run
+
+	flush := func(y int, keep map[run]bool) {
+		for r, y0 := range active {
+			if !keep[r] {
+				rects[r.color] = append(rects[r.color], cellRect{r.x0, y0, r.x1, y})
+				delete(active, r)
+			}
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		row = row[:0]
+		for x := 0; x < width; {
+			c := colorAt(x, y)
+			x1 := x + 1
+			for x1 < width && colorAt(x1, y) == c {
+				x1++
+			}
+			if c != skip {
+				row = append(row, run{x, x1, c})
+			}
+			x = x1
+		}
+
+		keep := make(map[run]bool, len(row))
+		for _, r := range row {
+			keep[r] = true
+		}
+		flush(y, keep)
+		for _, r := range row {
+			if _, ok := active[r]; !ok {
+				active[r] = y
+			}
+		}
+	}
+	flush(height, nil)
+
+	// Порядок обхода map случаен - сортируем для воспроизводимого вывода
+	for _, rs := range rects {
+		sort.Slice(rs, func(i, j int) bool {
+			if rs[i].y0 != rs[j].y0 {
+				return rs[i].y0 < rs[j].y0
+			}
+			return rs[i].x0 < rs[j].x0
+		})
+	}
+	return rects
+}
+
+// pathWaypoints возвращает концы пути и точки, в которых он меняет направление
+func pathWaypoints(path []*Node) []Point {
+	var points []Point
+	for i, node := range path {
+		if i == 0 || i == len(path)-1 {
+			points = append(points, node.Position)
+			continue
+		}
+		prev, next := path[i-1].Position, path[i+1].Position
+		cur := node.Position
+		if cur.x-prev.x != next.x-cur.x || cur.y-prev.y != next.y-cur.y {
+			points = append(points, cur)
+		}
+	}
+	return points
+}
+
+// DrawGridVector рисует сетку на векторном холсте размером width x height:
+// клетки одного цвета объединяются в прямоугольники, путь - ломаная
+// через точки поворота, старт и цель - круги. Размер клетки подбирается
+// под холст; заголовок и оси не рисуются.
+func DrawGridVector(c vg.Canvas, width, height vg.Length, grid *Grid, path []*Node, opts RenderOptions) {
+	opts = opts.withDefaults("", width, path)
+	margin := vg.Points(4)
+	cell := vg.Length(math.Min(
+		float64(width-2*margin)/float64(grid.Width),
+		float64(height-2*margin)/float64(grid.Height),
+	))
+	x0 := (width - cell*vg.Length(grid.Width)) / 2
+	y0 := (height - cell*vg.Length(grid.Height)) / 2
+	// center возвращает центр клетки; ось Y холста направлена вверх
+	center := func(p Point) vg.Point {
+		return vg.Point{
+			X: x0 + cell*(vg.Length(p.x)+0.5),
+			Y: y0 + cell*(vg.Length(grid.Height-p.y)-0.5),
+		}
+	}
+
+	// Клетки без пути и концов: они рисуются поверх линией и маркерами
+	cells := opts
+	cells.Start, cells.Goal = nil, nil
+	gd := newGridData(grid, nil, cells)
+	colors := opts.Palette.Colors()
+
+	fillRect := func(clr color.Color, r cellRect) {
+		var p vg.Path
+		left, right := x0+cell*vg.Length(r.x0), x0+cell*vg.Length(r.x1)
+		top, bottom := y0+cell*vg.Length(grid.Height-r.y0), y0+cell*vg.Length(grid.Height-r.y1)
+		p.Move(vg.Point{X: left, Y: bottom})
+		p.Line(vg.Point{X: right, Y: bottom})
+		p.Line(vg.Point{X: right, Y: top})
+		p.Line(vg.Point{X: left, Y: top})
+		p.Close()
+		c.SetColor(clr)
+		c.Fill(p)
+	}
+
+	fillRect(opts.Palette.Free, cellRect{0, 0, grid.Width, grid.Height})
+	rects := mergeRects(grid.Width, grid.Height, func(x, y int) int {
+		return gd.colorIndex(Point{x, y})
+	}, int(CellFree))
+	for idx := range colors {
+		for _, r := range rects[idx] {
+			fillRect(colors[idx], r)
+		}
+	}
+
+	// Линии сетки только если клетки достаточно крупные
+	if !opts.HideGrid && cell >= vg.Points(6) {
+		var lines vg.Path
+		for x := 0; x <= grid.Width; x++ {
+			lines.Move(vg.Point{X: x0 + cell*vg.Length(x), Y: y0})
+			lines.Line(vg.Point{X: x0 + cell*vg.Length(x), Y: y0 + cell*vg.Length(grid.Height)})
+		}
+		for y := 0; y <= grid.Height; y++ {
+			lines.Move(vg.Point{X: x0, Y: y0 + cell*vg.Length(y)})
+			lines.Line(vg.Point{X: x0 + cell*vg.Length(grid.Width), Y: y0 + cell*vg.Length(y)})
+		}
+		c.SetColor(color.Gray{200})
+		c.SetLineWidth(vg.Points(0.25))
+		c.Stroke(lines)
+	}
+
+	// Путь - ломаная только через точки поворота
+	waypoints := pathWaypoints(path)
+	if len(waypoints) > 1 {
+		var line vg.Path
+		for i, p := range waypoints {
+			if i == 0 {
+				line.Move(center(p))
+			} else {
+				line.Line(center(p))
+			}
+		}
+		c.SetColor(opts.Palette.Line)
+		c.SetLineWidth(vg.Length(math.Max(float64(cell)/3, 0.5)))
+		c.SetLineDash(nil, 0)
+		c.Stroke(line)
+	}
+
+	marker := func(p Point, clr color.Color) {
+		pt := center(p)
+		r := vg.Length(math.Max(float64(cell)*0.45, 1.5))
+		var circle vg.Path
+		circle.Move(vg.Point{X: pt.X + r, Y: pt.Y})
+		circle.Arc(pt, r, 0, 2*math.Pi)
+		circle.Close()
+		c.SetColor(clr)
+		c.Fill(circle)
+	}
+	if opts.Start != nil {
+		marker(*opts.Start, opts.Palette.Start)
+	}
+	if opts.Goal != nil {
+		marker(*opts.Goal, opts.Palette.Goal)
+	}
+
+	if opts.LabelWaypoints && len(waypoints) > 0 {
+		size := vg.Length(math.Min(math.Max(float64(cell)*0.8, 4), 10))
+		style := draw.TextStyle{
+			Color:   color.Black,
+			Font:    font.From(plot.DefaultFont, size),
+			Handler: plot.DefaultTextHandler,
+			XAlign:  draw.XLeft,
+			YAlign:  draw.YBottom,
+		}
+		dc := draw.New(sizedCanvas{c, width, height})
+		var last *Point
+		for i, p := range waypoints {
+			// Частые повороты лестницы не подписываем, чтобы подписи не слипались
+			if last != nil && i < len(waypoints)-1 && abs(p.x-last.x) < 3 && abs(p.y-last.y) < 3 {
+				continue
+			}
+			last = &waypoints[i]
+
+			// Подпись справа сверху от клетки, у краев сетки - внутрь
+			pt, sty := center(p), style
+			pt.X += cell / 2
+			pt.Y += cell / 2
+			if p.x >= grid.Width-3 {
+				pt.X -= cell
+				sty.XAlign = draw.XRight
+			}
+			if p.y < 2 {
+				pt.Y -= cell
+				sty.YAlign = draw.YTop
+			}
+			dc.FillText(sty, pt, fmt.Sprintf("%d,%d", p.x, p.y))
+		}
+	}
+}
+
+// sizedCanvas добавляет размер холсту для draw.New
+type sizedCanvas struct {
+	vg.Canvas
+	width, height vg.Length
+}
+
+func (c sizedCanvas) Size() (vg.Length, vg.Length) {
+	return c.width, c.height
+}
+
+// SaveGridVector сохраняет векторное изображение сетки в SVG или PDF
+// по расширению файла. Width задает ширину изображения (по умолчанию
+// 8 дюймов), высота по умолчанию следует пропорциям сетки.
+func SaveGridVector(filename string, grid *Grid, path []*Node, opts RenderOptions) error {
+	width, height := opts.Width, opts.Height
+	if width == 0 {
+		width = 8 * vg.Inch
+	}
+	if height == 0 {
+		height = width * vg.Length(grid.Height) / vg.Length(grid.Width)
+	}
+
+	var canvas interface {
+		vg.Canvas
+		io.WriterTo
+	}
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".svg":
+		canvas = vgsvg.New(width, height)
+	case ".pdf":
+		canvas = vgpdf.New(width, height)
+	default:
+		return fmt.Errorf("unsupported vector format %q", ext)
+	}
+	DrawGridVector(canvas, width, height, grid, path, opts)
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	_, err = canvas.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"image/color"
+	"io"
+	"os"
+	"sort"
+)
+
+//go:embed web/viewer.html
+var viewerHTML string
+
+var viewerTemplate = template.Must(template.New("viewer").Parse(viewerHTML))
+
+// ViewerOptions - параметры интерактивного просмотрщика
+type ViewerOptions struct {
+	Title       string
+	Start, Goal *Point
+	// Movement, Algorithm, Weight и Heuristic - начальные настройки поиска
+	// в браузере; пустая Heuristic - эвристика по умолчанию для движения
+	Movement  Movement
+	Algorithm Algorithm
+	Weight    float64
+	Heuristic string
+	// Trace - открытый и закрытый списки, показанные до первого поиска
+	// в браузере; nil - без них
+	Trace   *SearchTrace
+	Palette CellPalette
+}
+
+// viewerPalette - цвета просмотрщика как тройки RGB
+type viewerPalette struct {
+	Free        [3]uint8 `json:"free"`
+	Obstacle    [3]uint8 `json:"obstacle"`
+	Open        [3]uint8 `json:"open"`
+	Closed      [3]uint8 `json:"closed"`
+	Start       [3]uint8 `json:"start"`
+	Goal        [3]uint8 `json:"goal"`
+	Line        [3]uint8 `json:"line"`
+	TerrainLow  [3]uint8 `json:"terrainLow"`
+	TerrainHigh [3]uint8 `json:"terrainHigh"`
+}
+
+// viewerData - данные, встраиваемые в страницу как JSON
+type viewerData struct {
+	Title  string `json:"title"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Cells - по байту на клетку: 0 - препятствие, иначе номер в Costs
+	Cells []byte    `json:"cells"`
+	Costs []float64 `json:"costs"`
+	// Search - по байту на клетку: 0 - не достигнута, 1 - открыта, 2 - закрыта
+	Search    []byte        `json:"search"`
+	Path      [][2]int      `json:"path"`
+	Start     *[2]int       `json:"start"`
+	Goal      *[2]int       `json:"goal"`
+	Movement  int           `json:"movement"`
+	Algorithm string        `json:"algorithm"`
+	Weight    float64       `json:"weight"`
+	Heuristic string        `json:"heuristic"`
+	Result    string        `json:"result"`
+	Palette   viewerPalette `json:"palette"`
+}
+
+func rgb(c color.Color) [3]uint8 {
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return [3]uint8{nc.R, nc.G, nc.B}
+}
+
+func pointPair(p *Point) *[2]int {
+	if p == nil {
+		return nil
+	}
+	return &[2]int{p.x, p.y}
+}
+
+// viewerCells кодирует клетки номерами стоимостей. Если различных
+// стоимостей больше 255, они квантуются на 255 равных уровней между
+// наименьшей и наибольшей - поиск в браузере тогда приближенный.
+func viewerCells(grid *Grid) ([]byte, []float64) {
+	seen := make(map[float64]bool)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			if p := (Point{x, y}); !grid.IsObstacle(p) {
+				seen[grid.Cost(p)] = true
+			}
+		}
+	}
+	distinct := make([]float64, 0, len(seen))
+	for c := range seen {
+		distinct = append(distinct, c)
+	}
+	sort.Float64s(distinct)
+
+	// costs[0] не используется: номер 0 занят препятствием
+	costs := append([]float64{0}, distinct...)
+	quantized := len(distinct) > 255
+	var lo, hi float64
+	if quantized {
+		lo, hi = distinct[0], distinct[len(distinct)-1]
+		costs = make([]float64, 256)
+		for i := 1; i < len(costs); i++ {
+			costs[i] = lo + (hi-lo)*float64(i-1)/254
+		}
+	}
+	index := make(map[float64]byte, len(distinct))
+	for i, c := range distinct {
+		if quantized {
+			index[c] = byte(1 + int((c-lo)/(hi-lo)*254+0.5))
+		} else {
+			index[c] = byte(i + 1)
+		}
+	}
+
+	cells := make([]byte, grid.Width*grid.Height)
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			if p := (Point{x, y}); !grid.IsObstacle(p) {
+				cells[grid.index(p)] = index[grid.Cost(p)]
+			}
+		}
+	}
+	return cells, costs
+}
+
+// WriteViewerHTML выводит самодостаточную HTML-страницу с картой, путем
+// и поиском A* на JavaScript: карту можно сдвигать и масштабировать,
+// включать и выключать слои, а клик задает старт или цель и повторяет
+// поиск в браузере с теми же правилами шага, что и Searcher.
+func WriteViewerHTML(w io.Writer, grid *Grid, path []*Node, opts ViewerOptions) error {
+	if opts.Title == "" {
+		opts.Title = fmt.Sprintf("A* %dx%d", grid.Width, grid.Height)
+	}
+	if opts.Weight < 1 {
+		opts.Weight = 1
+	}
+	pal := opts.Palette.withDefaults()
+
+	data := viewerData{
+		Title:     opts.Title,
+		Width:     grid.Width,
+		Height:    grid.Height,
+		Path:      [][2]int{},
+		Start:     pointPair(opts.Start),
+		Goal:      pointPair(opts.Goal),
+		Movement:  4,
+		Algorithm: opts.Algorithm.String(),
+		Weight:    opts.Weight,
+		Heuristic: opts.Heuristic,
+		Palette: viewerPalette{
+			Free:        rgb(pal.Free),
+			Obstacle:    rgb(pal.Obstacle),
+			Open:        rgb(pal.Open),
+			Closed:      rgb(pal.Closed),
+			Start:       rgb(pal.Start),
+			Goal:        rgb(pal.Goal),
+			Line:        rgb(pal.Line),
+			TerrainLow:  rgb(pal.TerrainLow),
+			TerrainHigh: rgb(pal.TerrainHigh),
+		},
+	}
+	if opts.Movement == Moves8 {
+		data.Movement = 8
+	}
+	data.Cells, data.Costs = viewerCells(grid)
+	for _, node := range path {
+		data.Path = append(data.Path, [2]int{node.Position.x, node.Position.y})
+	}
+	if len(path) > 0 {
+		data.Result = fmt.Sprintf("путь: %d шагов, стоимость %.2f", len(path)-1, path[len(path)-1].GCost)
+	}
+	if t := opts.Trace; t != nil && t.Width == grid.Width && t.Height == grid.Height {
+		data.Search = make([]byte, grid.Width*grid.Height)
+		for _, p := range t.Open {
+			data.Search[grid.index(p)] = 1
+		}
+		for _, p := range t.Closed {
+			data.Search[grid.index(p)] = 2
+		}
+	}
+	return viewerTemplate.Execute(w, data)
+}
+
+// SaveViewerHTML сохраняет просмотрщик WriteViewerHTML в файл
+func SaveViewerHTML(filename string, grid *Grid, path []*Node, opts ViewerOptions) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = WriteViewerHTML(f, grid, path, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}